@@ -1,16 +1,22 @@
 package SSTable
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"kv/bloom"
+	"kv/filter"
+	"kv/index"
+	"kv/merkle_tree"
+	"kv/summary"
 	"log"
-	"main/bloom"
-	"main/index"
-	"main/merkle_tree"
-	"main/summary"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 type Entry struct {
@@ -20,14 +26,128 @@ type Entry struct {
 	value    []byte
 }
 
+//Hash funkcija za merkle stabla novih SSTabela, postojece tabele zadrzavaju algoritam iz svog zaglavlja
+var merkleHash = merkle_tree.SHA256
+
+//Inicijalizuje podesavanja merkle stabala iz eksterne konfiguracije
+func InitializeMerkleConfigs(algorithm merkle_tree.Algorithm) {
+	merkleHash = algorithm
+}
+
+//Tip filtera za nove SSTabele, tip postojecih se cita iz zaglavlja njihovog filtera
+var filterType = filter.Bloom
+
+//Inicijalizuje tip filtera iz eksterne konfiguracije
+func InitializeFilterConfigs(t filter.Type) {
+	filterType = t
+}
+
+//Ekstraktor prefiksa za prefiksne bloom filtere, nil znaci da se ne prave
+var prefixExtractor *bloom.PrefixExtractor
+
+//Inicijalizuje ekstraktor prefiksa iz eksterne konfiguracije
+//Otvorene tabele se zatvaraju, jer njihovi prefiksni filteri mozda vise ne odgovaraju ekstraktoru
+func InitializePrefixConfigs(extractor *bloom.PrefixExtractor) {
+	prefixExtractor = extractor
+	tables.clear()
+}
+
+//Velicina zaglavlja zapisa: crc 4, timestamp 8, tombstone 1, keySize 8, valueSize 8
+const headerSize = 29
+
+//Greska koja se vraca kada se CRC procitanog zapisa ne poklapa sa njegovim sadrzajem
+//Table je putanja do fajla (SSTabele ili WAL segmenta), Offset je pocetak zapisa u njemu
+type ErrCorrupted struct {
+	Table  string
+	Offset uint64
+}
+
+func (e *ErrCorrupted) Error() string {
+	return fmt.Sprintf("corrupted record in %s at offset %d", e.Table, e.Offset)
+}
+
+//CRC se racuna nad celim zapisom - zaglavljem bez samog CRC polja, kljucem i vrednoscu
+func CRC(record []byte) uint32 {
+	return crc32.ChecksumIEEE(record[4:])
+}
+
+//Proverava da li se CRC upisan u prva 4 bajta zapisa poklapa sa ostatkom zapisa
+func CheckCRC(record []byte) bool {
+	if len(record) < 4 {
+		return false
+	}
+	return binary.LittleEndian.Uint32(record[:4]) == CRC(record)
+}
+
+//Izdvaja iz zapisa polja koja pokriva list merkle stabla
+func recordData(record []byte) merkle_tree.Data {
+	keyLen := binary.LittleEndian.Uint64(record[13:21])
+	return merkle_tree.Data{
+		Key:       string(record[headerSize : headerSize+keyLen]),
+		Value:     string(record[headerSize+keyLen:]),
+		Timestamp: binary.LittleEndian.Uint64(record[4:12]),
+		Tombstone: record[12],
+	}
+}
+
+//Cita ceo zapis sa zadatog offseta i proverava njegov CRC
+//Vraca ceo zapis (zaglavlje, kljuc i vrednost) ili ErrCorrupted ukoliko je zapis ostecen
+func ReadRecord(file *os.File, offset uint64) ([]byte, error) {
+	size, err := fileSize(file)
+	if err != nil {
+		return nil, err
+	}
+	return readRecordAt(file, file.Name(), size, offset)
+}
+
+//Isto kao ReadRecord, ali cita iz bilo kog izvora (npr. preko blok kesa)
+//path se koristi samo za poruku o gresci, size je velicina celog fajla
+func readRecordAt(reader io.ReaderAt, path string, size int64, offset uint64) ([]byte, error) {
+	header := make([]byte, headerSize)
+	_, err := reader.ReadAt(header, int64(offset))
+	if err != nil {
+		if err == io.EOF {
+			return nil, &ErrCorrupted{Table: path, Offset: offset}
+		}
+		return nil, err
+	}
+	if header[12] > 1 {
+		return nil, &ErrCorrupted{Table: path, Offset: offset}
+	}
+	keyLen := binary.LittleEndian.Uint64(header[13:21])
+	valueLen := binary.LittleEndian.Uint64(header[21:29])
+	if keyLen+valueLen > uint64(size) || offset+headerSize+keyLen+valueLen > uint64(size) {
+		return nil, &ErrCorrupted{Table: path, Offset: offset}
+	}
+	record := make([]byte, headerSize+keyLen+valueLen)
+	copy(record, header)
+	_, err = reader.ReadAt(record[headerSize:], int64(offset+headerSize))
+	if err != nil {
+		return nil, err
+	}
+	if !CheckCRC(record) {
+		return nil, &ErrCorrupted{Table: path, Offset: offset}
+	}
+	return record, nil
+}
+
+func fileSize(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
 //Main funkcija za upis i kreiranje svih potrebnih fajlova i direktorijuma jedne SSTabele
 func MakeTable(memTable [][]byte, level int, bloomPer float64) {
 	//crc 4,timestamp 8,tombstone 1, keySize 8, valueSize 8, key, value
 	//Popunjavanje entys
 	last := FindLastFile(level)
 	name := strconv.Itoa(level) + "_" + strconv.Itoa(last)
+	//Ime je mozda ranije pripadalo tabeli koju je kompakcija obrisala
+	InvalidateTable(name)
 	entrys := make([]Entry, 0)
-	merkleValues := make([]merkle_tree.Data, 0)
 
 	for _, i := range memTable {
 		if int(i[12]) == 0 {
@@ -36,7 +156,6 @@ func MakeTable(memTable [][]byte, level int, bloomPer float64) {
 			temp.keyLen = binary.LittleEndian.Uint64(i[13:21])
 			temp.valueLen = binary.LittleEndian.Uint64(i[21:29])
 			temp.key = string(i[29 : 29+temp.keyLen])
-			merkleValues = append(merkleValues, merkle_tree.Data{Value: string(i[29+temp.keyLen : 29+temp.keyLen+temp.valueLen])})
 			entrys = append(entrys, temp)
 		}
 	}
@@ -58,18 +177,30 @@ func MakeTable(memTable [][]byte, level int, bloomPer float64) {
 	//Ako ne postoje, kreira ih
 	createFiles(name)
 
-	//Kreiranje bloom filtera, a zatim i upis
-	filter, seeds := bloom.NewBloom(keys, bloomPer)
-	bloom.WriteBloom(filter, seeds, name)
-	//test za bloom
-	//bl, seed := bloom.LoadBool(name)
-	//fmt.Println(bloom.IsInBloom(bl, "key5", seed))
+	//Kreiranje filtera, a zatim i upis
+	err := filter.Write(filter.New(filterType, keys, bloomPer), name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if prefixExtractor != nil {
+		err = bloom.WritePrefixBloom(bloom.NewPrefixBloom(keys, prefixExtractor, bloomPer), prefixExtractor, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if err := bloom.RemovePrefixBloom(name); err != nil {
+		log.Fatal(err)
+	}
 
 	//Kreirati merkle stablo
-	var node_list = merkle_tree.ToNodeList(merkleValues)
-	var mr = merkle_tree.BuildMerkle(node_list)
+	//Listovi idu redom kojim se zapisi upisuju u SSTabelu, kako bi provera mogla da ih rekonstruise iz fajla
+	merkleValues := make([]merkle_tree.Data, len(entrys))
+	for i, j := range entrys {
+		merkleValues[i] = recordData(j.value)
+	}
+	var node_list = merkle_tree.ToNodeList(merkleValues, merkleHash)
+	var mr = merkle_tree.BuildMerkle(node_list, merkleHash)
 	var tree_list = merkle_tree.TreeToList(mr.Root)
-	merkle_tree.Serialize(tree_list, "data/SSTable"+name+"/metadata"+name+".txt")
+	merkle_tree.Serialize(tree_list, merkleHash, "data/SSTable"+name+"/metadata"+name+".txt")
 
 	//Upis indexa na disk
 	entrysLen := make([]uint64, len(entrys))
@@ -89,104 +220,259 @@ func MakeTable(memTable [][]byte, level int, bloomPer float64) {
 	writeSSTable(entrys, name)
 }
 
-func Find(key string, max int) ([]byte, bool) {
-	for i := 1; i < max; i++ {
+/*Trazi kljuc na nivoima od 1 do max, ukljucujuci max - kompakcija spaja nivo L u nivo L+1 za svako L < max,
+pa poslednji nivo sadrzi najstarije tabele. Isto vazi za Delete i skeniranja. Na nivou se tabele obilaze od najnovije*/
+func Find(key string, max int) ([]byte, bool, error) {
+	for i := 1; i <= max; i++ {
 		for j := FindLastFile(i) - 1; j > 0; j-- {
 			name := strconv.Itoa(i) + "_" + strconv.Itoa(j)
-			filter, seeds := bloom.LoadBool(name)
-			if bloom.IsInBloom(filter, key, seeds) {
-				offset, found := summary.Find(key, name)
-				if found {
-					offset = index.Find(offset, name)
-					if found {
-						return findInTable(offset, name)
-					}
-				}
+			_, value, found, err := locate(name, key)
+			if err != nil || found {
+				return value, found, err
 			}
 		}
 	}
 	data := make([]byte, 0)
-	return data, false
+	return data, false, nil
 }
 
-func Delete(key string, max int) bool {
-	for i := 1; i < max; i++ {
+//Trazi kljuc u jednoj tabeli preko kesa tabela - filter, summary, index pa data fajl kroz blok kes
+//Vraca i offset zapisa u data fajlu
+func locate(name string, key string) (uint64, []byte, bool, error) {
+	t, err := tables.get(name)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	defer tables.release(t)
+	if !t.filter.MayContain(key) {
+		return 0, nil, false, nil
+	}
+	offset, found := t.summary.Find(key)
+	if !found {
+		return 0, nil, false, nil
+	}
+	offset, err = index.FindIn(t.index, offset)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	value, found, err := findInTable(t, offset)
+	return offset, value, found, err
+}
+
+//Vraca najnovije zapise svih kljuceva sa zadatim prefiksom iz SSTabela, sortirane po kljucu
+//Vraca i obrisane zapise (tombstone), kako bi ih pozivalac mogao sakriti, i broj tabela
+//koje je prefiksni filter preskocio
+func PrefixScan(prefix string, max int) ([][]byte, int, error) {
+	seen := make(map[string]bool)
+	records := make([][]byte, 0)
+	skipped := 0
+	for i := 1; i <= max; i++ {
 		for j := FindLastFile(i) - 1; j > 0; j-- {
 			name := strconv.Itoa(i) + "_" + strconv.Itoa(j)
-			filter, seeds := bloom.LoadBool(name)
-			if bloom.IsInBloom(filter, key, seeds) {
-				offset, found := summary.Find(key, name)
-				if found {
-					offset = index.Find(offset, name)
-					if found {
-						_, found = findInTable(offset, name)
-						if found { //Same as Find()
-							return deleteAt(offset, name)
-						}
-					}
+			found, skip, err := scanTable(name, prefix)
+			if err != nil {
+				return nil, skipped, err
+			}
+			if skip {
+				skipped++
+			}
+			//Tabele se obilaze od najnovije, pa prvi zapis kljuca vazi
+			for _, record := range found {
+				key := recordData(record).Key
+				if !seen[key] {
+					seen[key] = true
+					records = append(records, record)
 				}
 			}
 		}
 	}
-	return false
+	sort.Slice(records, func(a, b int) bool {
+		return recordData(records[a]).Key < recordData(records[b]).Key
+	})
+	return records, skipped, nil
 }
 
-func deleteAt(offset uint64, name string) bool {
-	file, err := os.Open("data/SSTable" + name + "/SSTable" + name + ".txt")
+//Cita zapise jedne tabele sa zadatim prefiksom, ili je preskace ako prefiksni filter kaze da ih nema
+func scanTable(name string, prefix string) ([][]byte, bool, error) {
+	t, err := tables.get(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tables.release(t)
+	if t.prefixes != nil {
+		if p, ok := prefixExtractor.Extract(prefix); ok && !t.prefixes.IsInBloom(p) {
+			return nil, true, nil
+		}
+	}
+
+	records := make([][]byte, 0)
+	it := &TableIterator{t: t}
+	err = it.Seek(prefix)
+	if err != nil {
+		return nil, false, err
+	}
+	for {
+		record, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if !strings.HasPrefix(recordData(record).Key, prefix) {
+			break
+		}
+		records = append(records, record)
+	}
+	return records, false, nil
+}
+
+/*Vraca najnovije zapise svih kljuceva iz opsega [start, end) iz SSTabela, sortirane po kljucu
+Prazan end znaci opseg bez gornje granice. Kao i PrefixScan, vraca i obrisane zapise
+Sa limit > 0 iz svake tabele se cita najvise limit zapisa, pa se vracaju samo kljucevi do najmanjeg
+poslednjeg procitanog kljuca skracenih tabela - za njih je svaka tabela dala sve verzije. Tada je next
+kljuc od kog se nastavlja, a prazan next znaci da je opseg procitan do kraja*/
+func RangeScan(start string, end string, max int, limit int) ([][]byte, string, error) {
+	seen := make(map[string]bool)
+	records := make([][]byte, 0)
+	cutoff, truncated := "", false
+	for i := 1; i <= max; i++ {
+		for j := FindLastFile(i) - 1; j > 0; j-- {
+			name := strconv.Itoa(i) + "_" + strconv.Itoa(j)
+			found, err := rangeTable(name, start, end, limit)
+			if err != nil {
+				return nil, "", err
+			}
+			if limit > 0 && len(found) == limit {
+				last := recordData(found[len(found)-1]).Key
+				if !truncated || last < cutoff {
+					cutoff, truncated = last, true
+				}
+			}
+			for _, record := range found {
+				key := recordData(record).Key
+				if !seen[key] {
+					seen[key] = true
+					records = append(records, record)
+				}
+			}
+		}
+	}
+	sort.Slice(records, func(a, b int) bool {
+		return recordData(records[a]).Key < recordData(records[b]).Key
+	})
+	if !truncated {
+		return records, "", nil
+	}
+	n := sort.Search(len(records), func(k int) bool { return recordData(records[k]).Key > cutoff })
+	//Najmanji kljuc veci od cutoff
+	return records[:n], cutoff + "\x00", nil
+}
+
+//Cita zapise jedne tabele iz opsega [start, end), najvise limit (0 - bez ogranicenja)
+func rangeTable(name string, start string, end string, limit int) ([][]byte, error) {
+	t, err := tables.get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer tables.release(t)
+
+	records := make([][]byte, 0)
+	it := &TableIterator{t: t}
+	err = it.Seek(start)
+	if err != nil {
+		return nil, err
+	}
+	for limit <= 0 || len(records) < limit {
+		record, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if end != "" && recordData(record).Key >= end {
+			break
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func Delete(key string, max int) (bool, error) {
+	for i := 1; i <= max; i++ {
+		for j := FindLastFile(i) - 1; j > 0; j-- {
+			name := strconv.Itoa(i) + "_" + strconv.Itoa(j)
+			offset, _, found, err := locate(name, key)
+			if err != nil {
+				return false, err
+			}
+			if found { //Same as Find()
+				return deleteAt(offset, name)
+			}
+		}
+	}
+	return false, nil
+}
+
+func deleteAt(offset uint64, name string) (bool, error) {
+	file, err := os.OpenFile("data/SSTable"+name+"/SSTable"+name+".txt", os.O_RDWR, 0666)
+	if err != nil {
+		return false, err
+	}
 	defer file.Close()
+	record, err := ReadRecord(file, offset)
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
-	file.Seek(int64(offset), 0)
-	bytes := make([]byte, 12)
-	file.Read(bytes) //Read before keyLen 12B
-	bytes = make([]byte, 1)
-	file.Read(bytes) //Tombstone
-	toombstone := bytes[0]
+	toombstone := record[12]
 	if int(toombstone) == 0 { //Same as findInTable()
-		//Overwrite toombstone
-		file, err = os.OpenFile("data/SSTable"+name+"/SSTable"+name+".txt", os.O_WRONLY, 0666)
+		//Overwrite toombstone, CRC pokriva i tombstone pa se i on ponovo racuna
+		record[12] = byte(1)
+		binary.LittleEndian.PutUint32(record[:4], CRC(record))
+		_, err = file.WriteAt(record[:13], int64(offset))
 		if err != nil {
-			panic(err)
+			return false, err
 		}
-		defer file.Close()
-		file.Seek(int64(offset+12), 0)
-		bytes = make([]byte, 1)
-		bytes[0] = byte(1)
-		file.Write(bytes)
-		return true
+		//Blokovi otvorene tabele u kesu sadrze stari tombstone
+		InvalidateTable(name)
+		//List merkle stabla pokriva i tombstone, pa se metadata mora ponovo izgraditi
+		return true, writeMetadata(name)
 	}
-	return false
+	return false, nil
 }
 
-func findInTable(offset uint64, name string) ([]byte, bool) {
+//Ponovo gradi merkle stablo iz trenutnog sadrzaja SSTabele i upisuje ga u metadata datoteku
+//Zadrzava se algoritam kojim je tabela napravljena
+func writeMetadata(name string) error {
+	records, err := readTable("data", name)
+	if err != nil {
+		return err
+	}
+	metadata := "data/SSTable" + name + "/metadata" + name + ".txt"
+	_, algorithm, err := merkle_tree.Deserialize(metadata)
+	if err != nil {
+		algorithm = merkleHash
+	}
+	mr := merkle_tree.BuildMerkle(merkle_tree.ToNodeList(records.leaves, algorithm), algorithm)
+	merkle_tree.Serialize(merkle_tree.TreeToList(mr.Root), algorithm, metadata)
+	return nil
+}
+
+func findInTable(t *table, offset uint64) ([]byte, bool, error) {
 	value := make([]byte, 0)
 	found := false
-	file, err := os.Open("data/SSTable" + name + "/SSTable" + name + ".txt")
-	defer file.Close()
+	record, err := readRecordAt(t.reader(), t.data.Name(), t.dataSize, offset)
 	if err != nil {
-		log.Fatal(err)
+		return value, false, err
 	}
-	file.Seek(int64(offset), 0)
-	bytes := make([]byte, 12)
-	file.Read(bytes) //Read before keyLen 12B
-	bytes = make([]byte, 1)
-	file.Read(bytes) //Tombstone
-	toombstone := bytes[0]
+	toombstone := record[12]
 	if int(toombstone) == 0 {
-		bytes = make([]byte, 8)
-		file.Read(bytes) //KeyLen8B
-		keyLen := binary.LittleEndian.Uint64(bytes)
-		file.Read(bytes) //ValueLen8B
-		valueLen := binary.LittleEndian.Uint64(bytes)
-		bytes = make([]byte, keyLen)
-		file.Read(bytes) //Key
-		bytes = make([]byte, valueLen)
-		file.Read(bytes) //Value
-		value = bytes
+		keyLen := binary.LittleEndian.Uint64(record[13:21])
+		value = record[headerSize+keyLen:]
 		found = true
 	}
-	return value, found
+	return value, found, nil
 }
 
 func writeSSTable(entrys []Entry, name string) {
@@ -283,6 +569,169 @@ func createFiles(name string) {
 	}
 }
 
+//Jedan zapis (list merkle stabla) koji se ne poklapa sa sacuvanom metadata datotekom
+//Leaf je redni broj zapisa u SSTabeli, a -1 ako je ostecena sama metadata datoteka
+type Mismatch struct {
+	Table  string
+	Leaf   int
+	Key    string
+	Offset uint64
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	if m.Leaf < 0 {
+		return "SSTable" + m.Table + ": " + m.Reason
+	}
+	return fmt.Sprintf("SSTable%s: record %d (key %q, offset %d): %s", m.Table, m.Leaf, m.Key, m.Offset, m.Reason)
+}
+
+//Zapisi jedne SSTabele redom kojim su upisani u fajl, zajedno sa blokovima za listove merkle stabla
+type tableRecords struct {
+	keys    []string
+	offsets []uint64
+	leaves  []merkle_tree.Data
+}
+
+//Cita sve zapise SSTabele redom
+//Za ostecen zapis vraca do tada procitane zapise i ErrCorrupted
+func readTable(dir string, name string) (*tableRecords, error) {
+	records := &tableRecords{keys: make([]string, 0), offsets: make([]uint64, 0), leaves: make([]merkle_tree.Data, 0)}
+	file, err := os.Open(dir + "/SSTable" + name + "/SSTable" + name + ".txt")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	size, err := fileSize(file)
+	if err != nil {
+		return nil, err
+	}
+
+	for offset := uint64(0); offset < uint64(size); {
+		record, err := ReadRecord(file, offset)
+		if err != nil {
+			return records, err
+		}
+		leaf := recordData(record)
+		records.keys = append(records.keys, leaf.Key)
+		records.offsets = append(records.offsets, offset)
+		records.leaves = append(records.leaves, leaf)
+		offset += uint64(len(record))
+	}
+	return records, nil
+}
+
+//Ponovo gradi merkle stablo od podataka SSTabele i poredi ga sa sacuvanom metadata datotekom
+//Vraca zapise koji se razlikuju, prazna lista znaci da je tabela ispravna
+//Kod starih metadata (merkle_tree.ErrLegacyMetadata) porede se samo vrednosti, a ako se poklapaju vraca se ta greska
+func VerifyTable(name string) ([]Mismatch, error) {
+	records, err := readTable("data", name)
+	if err != nil {
+		if corrupted, ok := err.(*ErrCorrupted); ok {
+			//Posle ostecenog zaglavlja ne zna se gde pocinje sledeci zapis
+			return []Mismatch{{Table: name, Leaf: len(records.keys), Offset: corrupted.Offset, Reason: "record CRC mismatch"}}, nil
+		}
+		return nil, err
+	}
+
+	stored, algorithm, err := merkle_tree.Deserialize("data/SSTable" + name + "/metadata" + name + ".txt")
+	legacy := errors.Is(err, merkle_tree.ErrLegacyMetadata)
+	if err != nil && !legacy {
+		return []Mismatch{{Table: name, Leaf: -1, Reason: err.Error()}}, nil
+	}
+	leaves := merkle_tree.ToNodeList(records.leaves, algorithm)
+	if legacy {
+		leaves = merkle_tree.ToLegacyNodeList(records.leaves)
+	}
+	mr := merkle_tree.BuildMerkle(leaves, algorithm)
+	diff, err := merkle_tree.Diff(mr.Root, stored)
+	if err != nil {
+		return []Mismatch{{Table: name, Leaf: -1, Reason: err.Error()}}, nil
+	}
+
+	mismatches := make([]Mismatch, 0)
+	for _, leaf := range diff {
+		if leaf < 0 || leaf >= len(records.keys) {
+			mismatches = append(mismatches, Mismatch{Table: name, Leaf: -1, Reason: "metadata hash mismatch"})
+			continue
+		}
+		mismatches = append(mismatches, Mismatch{Table: name, Leaf: leaf, Key: records.keys[leaf], Offset: records.offsets[leaf], Reason: "hash mismatch"})
+	}
+	//Vrednosti se poklapaju sa starim stablom, ali ono ne pokriva kljuceve, vremena ni tombstone
+	if legacy && len(mismatches) == 0 {
+		return nil, merkle_tree.ErrLegacyMetadata
+	}
+	return mismatches, nil
+}
+
+//Ponovo gradi metadata datoteku tabele sa starim metadata, tek posto se njene vrednosti poklope sa starim stablom
+func RebuildLegacyMetadata(name string) error {
+	mismatches, err := VerifyTable(name)
+	if err == nil && len(mismatches) == 0 {
+		return errors.New("SSTable" + name + " does not have legacy metadata")
+	}
+	if !errors.Is(err, merkle_tree.ErrLegacyMetadata) {
+		if err == nil {
+			err = errors.New("SSTable" + name + " does not match its metadata, run verify")
+		}
+		return err
+	}
+	return writeMetadata(name)
+}
+
+//Pravi dokaz pripadnosti kljuca SSTabeli name
+//Vraca dokaz, hash korena iz metadata datoteke (onaj koji se potpisuje) i zapis koji list pokriva
+//Ukoliko se koren izgradjen iz podataka ne poklapa sa sacuvanim, dokaz se ne pravi
+func Prove(key string, name string) (*merkle_tree.Proof, []byte, *merkle_tree.Data, error) {
+	var root []byte
+	records, err := readTable("data", name)
+	if err != nil {
+		return nil, root, nil, err
+	}
+	leaf := sort.SearchStrings(records.keys, key)
+	if leaf == len(records.keys) || records.keys[leaf] != key {
+		return nil, root, nil, errors.New("key " + key + " not found in SSTable" + name)
+	}
+
+	stored, algorithm, err := merkle_tree.Deserialize("data/SSTable" + name + "/metadata" + name + ".txt")
+	if err != nil {
+		return nil, root, nil, err
+	}
+	proof, root, err := merkle_tree.BuildProof(merkle_tree.ToNodeList(records.leaves, algorithm), leaf, algorithm)
+	if err != nil {
+		return nil, root, nil, err
+	}
+	if len(stored) == 0 || !bytes.Equal(stored[0], root) {
+		return nil, root, nil, errors.New("SSTable" + name + " does not match its metadata, run verify")
+	}
+	return proof, root, &records.leaves[leaf], nil
+}
+
+//Proverava sve SSTabele na nivoima od 1 do maxLevel
+//Vraca listu svih zapisa koji se razlikuju, tabele sa starim metadata cije se vrednosti poklapaju i broj proverenih tabela
+func VerifyAll(maxLevel int) ([]Mismatch, []string, int, error) {
+	mismatches := make([]Mismatch, 0)
+	legacy := make([]string, 0)
+	checked := 0
+	for i := 1; i <= maxLevel; i++ {
+		for j := 1; j < FindLastFile(i); j++ {
+			name := strconv.Itoa(i) + "_" + strconv.Itoa(j)
+			diff, err := VerifyTable(name)
+			if errors.Is(err, merkle_tree.ErrLegacyMetadata) {
+				legacy = append(legacy, name)
+				checked++
+				continue
+			}
+			if err != nil {
+				return mismatches, legacy, checked, err
+			}
+			mismatches = append(mismatches, diff...)
+			checked++
+		}
+	}
+	return mismatches, legacy, checked, nil
+}
+
 func FindLastFile(level int) int {
 	for j := 1; ; j++ {
 		name := strconv.Itoa(level) + "_" + strconv.Itoa(j)
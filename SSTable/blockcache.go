@@ -0,0 +1,158 @@
+package SSTable
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sync"
+)
+
+//Velicina bloka u kojima se data fajl SSTabele cita i kesira
+const blockSize = 4096
+
+//Blok je odredjen tabelom (id otvorene tabele, ne ime) i offsetom pocetka bloka u data fajlu
+type blockKey struct {
+	table  uint64
+	offset int64
+}
+
+type block struct {
+	key  blockKey
+	data []byte
+}
+
+//LRU kes blokova data fajlova SSTabela ogranicen brojem bajtova
+//Nalazi se ispod findInTable i iteratora, pa koristi i pretragama i skeniranjima
+type blockCache struct {
+	mutex     sync.Mutex
+	blocks    map[blockKey]*list.Element
+	order     *list.List
+	maxBytes  int64
+	bytes     int64
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+var blocks = &blockCache{
+	blocks:   make(map[blockKey]*list.Element),
+	order:    list.New(),
+	maxBytes: 4 << 20,
+}
+
+//Statistika blok kesa - sluzi za odredjivanje njegove velicine
+type BlockCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Blocks    int
+	Bytes     int64
+	MaxBytes  int64
+}
+
+func (stats BlockCacheStats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+func (stats BlockCacheStats) String() string {
+	return fmt.Sprintf("block cache: %d hits, %d misses (%.1f%% hit ratio), %d evictions, %d blocks, %d/%d bytes",
+		stats.Hits, stats.Misses, stats.HitRatio()*100, stats.Evictions, stats.Blocks, stats.Bytes, stats.MaxBytes)
+}
+
+//Inicijalizuje velicinu blok kesa iz eksterne konfiguracije, 0 iskljucuje kes
+func InitializeBlockCacheConfigs(maxBytes int64) {
+	blocks.mutex.Lock()
+	defer blocks.mutex.Unlock()
+	blocks.maxBytes = maxBytes
+	blocks.evict()
+}
+
+func BlockCacheStatistics() BlockCacheStats {
+	blocks.mutex.Lock()
+	defer blocks.mutex.Unlock()
+	return BlockCacheStats{
+		Hits:      blocks.hits,
+		Misses:    blocks.misses,
+		Evictions: blocks.evictions,
+		Blocks:    blocks.order.Len(),
+		Bytes:     blocks.bytes,
+		MaxBytes:  blocks.maxBytes,
+	}
+}
+
+//Vraca blok tabele koji pocinje na offsetu (offset je poravnat na blockSize)
+//Poslednji blok fajla moze biti kraci od blockSize
+func (cache *blockCache) read(t *table, offset int64) ([]byte, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	key := blockKey{table: t.id, offset: offset}
+	if element, found := cache.blocks[key]; found {
+		cache.hits++
+		cache.order.MoveToBack(element)
+		return element.Value.(*block).data, nil
+	}
+	cache.misses++
+
+	data := make([]byte, blockSize)
+	n, err := t.data.ReadAt(data, offset)
+	if err != nil && !(err == io.EOF && n > 0) {
+		return nil, err
+	}
+	data = data[:n]
+	if cache.maxBytes > 0 {
+		cache.blocks[key] = cache.order.PushBack(&block{key: key, data: data})
+		cache.bytes += int64(len(data))
+		cache.evict()
+	}
+	return data, nil
+}
+
+func (cache *blockCache) evict() {
+	for cache.order.Len() > 0 && cache.bytes > cache.maxBytes {
+		cache.remove(cache.order.Front())
+		cache.evictions++
+	}
+}
+
+func (cache *blockCache) remove(element *list.Element) {
+	b := element.Value.(*block)
+	cache.order.Remove(element)
+	delete(cache.blocks, b.key)
+	cache.bytes -= int64(len(b.data))
+}
+
+//Izbacuje sve blokove zatvorene tabele
+func (cache *blockCache) dropTable(id uint64) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for key, element := range cache.blocks {
+		if key.table == id {
+			cache.remove(element)
+		}
+	}
+}
+
+//Citac data fajla jedne tabele preko blok kesa
+type blockReader struct {
+	t *table
+}
+
+func (reader blockReader) ReadAt(p []byte, offset int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		position := offset + int64(n)
+		start := position - position%blockSize
+		data, err := blocks.read(reader.t, start)
+		if err != nil {
+			return n, err
+		}
+		if position-start >= int64(len(data)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], data[position-start:])
+	}
+	return n, nil
+}
@@ -0,0 +1,418 @@
+package SSTable
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"kv/merkle_tree"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Razlika jednog kljuca izmedju dve kopije baze (npr. glavne baze i replike)
+//InFirst i InSecond govore da li strana sadrzi kljuc, ako su oba true najnovije verzije se razlikuju
+type KeyDiff struct {
+	Key      string
+	InFirst  bool
+	InSecond bool
+}
+
+func (d KeyDiff) String() string {
+	switch {
+	case d.InFirst && d.InSecond:
+		return fmt.Sprintf("%q differs", d.Key)
+	case d.InFirst:
+		return fmt.Sprintf("%q only in first", d.Key)
+	default:
+		return fmt.Sprintf("%q only in second", d.Key)
+	}
+}
+
+//Broj kofa u koje se kljucevi rasporedjuju po hash-u kljuca. Oblik stabla kofa ne zavisi od broja kljuceva
+//ni od rasporeda flush-a i kompakcija, pa se stabla dve baze porede cvor po cvor
+const diffBuckets = 1024
+
+//Stablo kofa se ne cuva, pa se uvek gradi istim algoritmom bez obzira na konfiguraciju baze
+const diffAlgorithm = merkle_tree.SHA256
+
+//Najnovija verzija jednog kljuca: kljuc i hash lista (kljuc, vrednost, timestamp i tombstone)
+type KeyHash struct {
+	Key  string
+	Hash []byte
+}
+
+//Baza koja se poredi - lokalni pogled (StoreView) ili instanca koja radi, preko lokalnog socket-a (RemoteStore)
+type StoreSource interface {
+	Tree() ([][]byte, error)                    //stablo kofa, breadth first kao merkle_tree.TreeToList
+	Buckets(buckets []int) ([][]KeyHash, error) //sadrzaj trazenih kofa, sortiran po kljucu
+}
+
+/*Pogled na bazu kakvu vide citanja: najnovija verzija svakog kljuca iz svih SSTabela (i memtabele instance koja radi)
+Obrisani kljucevi se izostavljaju, pa se poredi sadrzaj, a ne to kojim redom su zapisi flush-ovani i kompaktovani
+Pogled drzi u memoriji sve kljuceve baze sa hash-em najnovije verzije - O(n) memorije i citanje svih zapisa,
+bez obzira na to koliko se baze razlikuju. Sacuvana merkle stabla SSTabela se ovde ne koriste: dve kopije iste baze
+obicno imaju razlicit raspored tabela (flush, kompakcije), pa se njihova stabla ne mogu porediti cvor po cvor*/
+type StoreView struct {
+	newest  map[string]merkle_tree.Data
+	buckets [][]KeyHash
+}
+
+//Cita sve zapise svih SSTabela iz data direktorijuma - za svaki kljuc ostaje verzija iz najnovije tabele
+//Tabele se obilaze kao u Find: nizi nivo pre viseg, a u okviru nivoa novija tabela pre starije
+func LoadStoreView(dir string) (*StoreView, error) {
+	names, err := listTables(dir)
+	if err != nil {
+		return nil, err
+	}
+	view := &StoreView{newest: make(map[string]merkle_tree.Data)}
+	for _, name := range names {
+		records, err := readTable(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, leaf := range records.leaves {
+			if _, found := view.newest[leaf.Key]; !found {
+				view.newest[leaf.Key] = leaf
+			}
+		}
+	}
+	return view, nil
+}
+
+//Dodaje zapise novije od tabela (zapise memtabele, u formatu WAL-a), kasniji zapis istog kljuca je noviji
+func (view *StoreView) Overlay(records [][]byte) {
+	for _, record := range records {
+		leaf := recordData(record)
+		view.newest[leaf.Key] = leaf
+	}
+	view.buckets = nil
+}
+
+func bucketOf(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % diffBuckets)
+}
+
+func (view *StoreView) fill() {
+	if view.buckets != nil {
+		return
+	}
+	view.buckets = make([][]KeyHash, diffBuckets)
+	for key, leaf := range view.newest {
+		if leaf.Tombstone == 1 {
+			continue
+		}
+		b := bucketOf(key)
+		view.buckets[b] = append(view.buckets[b], KeyHash{Key: key, Hash: merkle_tree.HashLeaf(leaf, diffAlgorithm)})
+	}
+	for _, bucket := range view.buckets {
+		sort.Slice(bucket, func(i, j int) bool { return bucket[i].Key < bucket[j].Key })
+	}
+}
+
+//Hash kofe je hash nadovezanih hash-eva listova, redom po kljucu
+func (view *StoreView) Tree() ([][]byte, error) {
+	view.fill()
+	hashes := make([][]byte, diffBuckets)
+	for i, bucket := range view.buckets {
+		concatenated := make([]byte, 0, len(bucket)*diffAlgorithm.Size())
+		for _, kh := range bucket {
+			concatenated = append(concatenated, kh.Hash...)
+		}
+		hashes[i] = merkle_tree.Hash(diffAlgorithm, concatenated)
+	}
+	root := merkle_tree.BuildMerkle(merkle_tree.HashNodeList(hashes), diffAlgorithm)
+	return merkle_tree.TreeToList(root.Root), nil
+}
+
+func (view *StoreView) Buckets(buckets []int) ([][]KeyHash, error) {
+	view.fill()
+	result := make([][]KeyHash, len(buckets))
+	for i, b := range buckets {
+		if b < 0 || b >= diffBuckets {
+			return nil, fmt.Errorf("bucket %d out of range", b)
+		}
+		result[i] = view.buckets[b]
+	}
+	return result, nil
+}
+
+/*Poredi dve baze: spusta se kroz stablo kofa samo u podstabla ciji se hash razlikuje,
+pa preko socket-a prenosi samo stablo i kljuceve i hash-eve iz kofa koje se razlikuju
+Srazmeran razlikama je samo taj prenos - svaka strana za stablo kofa svejedno cita celu bazu (LoadStoreView)
+Vraca kljuceve koji se razlikuju, sortirane*/
+func DiffSources(first StoreSource, second StoreSource) ([]KeyDiff, error) {
+	firstTree, err := first.Tree()
+	if err != nil {
+		return nil, err
+	}
+	secondTree, err := second.Tree()
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := merkle_tree.DiffLists(firstTree, secondTree, diffBuckets)
+	if err != nil {
+		return nil, err
+	}
+	diffs := make([]KeyDiff, 0)
+	if len(buckets) == 0 {
+		return diffs, nil
+	}
+	for _, b := range buckets {
+		if b < 0 {
+			return nil, errors.New("bucket tree is damaged")
+		}
+	}
+	firstBuckets, err := first.Buckets(buckets)
+	if err != nil {
+		return nil, err
+	}
+	secondBuckets, err := second.Buckets(buckets)
+	if err != nil {
+		return nil, err
+	}
+	if len(firstBuckets) != len(buckets) || len(secondBuckets) != len(buckets) {
+		return nil, errors.New("store returned wrong number of buckets")
+	}
+	for i := range buckets {
+		diffs = append(diffs, diffBucket(firstBuckets[i], secondBuckets[i])...)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs, nil
+}
+
+//Poredi sadrzaj iste kofe na dve strane - obe su sortirane po kljucu
+func diffBucket(first []KeyHash, second []KeyHash) []KeyDiff {
+	diffs := make([]KeyDiff, 0)
+	i, j := 0, 0
+	for i < len(first) || j < len(second) {
+		switch {
+		case j == len(second) || (i < len(first) && first[i].Key < second[j].Key):
+			diffs = append(diffs, KeyDiff{Key: first[i].Key, InFirst: true})
+			i++
+		case i == len(first) || second[j].Key < first[i].Key:
+			diffs = append(diffs, KeyDiff{Key: second[j].Key, InSecond: true})
+			j++
+		default:
+			if !bytes.Equal(first[i].Hash, second[j].Hash) {
+				diffs = append(diffs, KeyDiff{Key: first[i].Key, InFirst: true, InSecond: true})
+			}
+			i++
+			j++
+		}
+	}
+	return diffs
+}
+
+/*Poredi dve kopije baze (dva data direktorijuma) koje ne rade
+Ako obe imaju iste tabele sa istim korenima merkle stabala, baze su iste i podaci se ne citaju - to je jedini
+slucaj u kom se koriste sacuvana merkle stabla. Inace se porede pogledi na najnovije verzije kljuceva, sa cenom LoadStoreView*/
+func DiffStores(first string, second string) ([]KeyDiff, error) {
+	same, err := sameTables(first, second)
+	if err != nil {
+		return nil, err
+	}
+	if same {
+		return make([]KeyDiff, 0), nil
+	}
+	firstView, err := LoadStoreView(first)
+	if err != nil {
+		return nil, err
+	}
+	secondView, err := LoadStoreView(second)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSources(firstView, secondView)
+}
+
+//Da li dva direktorijuma imaju iste tabele sa istim korenima sacuvanih merkle stabala
+func sameTables(first string, second string) (bool, error) {
+	firstTables, err := listTables(first)
+	if err != nil {
+		return false, err
+	}
+	secondTables, err := listTables(second)
+	if err != nil {
+		return false, err
+	}
+	if len(firstTables) != len(secondTables) {
+		return false, nil
+	}
+	for i, name := range firstTables {
+		if secondTables[i] != name {
+			return false, nil
+		}
+		//Staro stablo ne pokriva kljuceve, pa isti koren ne znaci iste tabele
+		firstTree, firstAlgorithm, err := merkle_tree.Deserialize(first + "/SSTable" + name + "/metadata" + name + ".txt")
+		if errors.Is(err, merkle_tree.ErrLegacyMetadata) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		secondTree, secondAlgorithm, err := merkle_tree.Deserialize(second + "/SSTable" + name + "/metadata" + name + ".txt")
+		if errors.Is(err, merkle_tree.ErrLegacyMetadata) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if firstAlgorithm != secondAlgorithm || len(firstTree) == 0 || len(secondTree) == 0 || !bytes.Equal(firstTree[0], secondTree[0]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//Imena svih SSTabela u data direktorijumu, od najnovije: nizi nivo pre viseg, a u okviru nivoa novija pre starije
+func listTables(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type position struct {
+		name         string
+		level, index int
+	}
+	tables := make([]position, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "SSTable") {
+			continue
+		}
+		name := strings.TrimPrefix(entry.Name(), "SSTable")
+		parts := strings.Split(name, "_")
+		if len(parts) != 2 {
+			continue
+		}
+		level, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		index, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		tables = append(tables, position{name, level, index})
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].level != tables[j].level {
+			return tables[i].level < tables[j].level
+		}
+		return tables[i].index > tables[j].index
+	})
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.name
+	}
+	return names, nil
+}
+
+/*Poredjenje sa instancom koja radi, preko lokalnog (unix) socket-a
+Klijent salje zahteve, a server na svaki odgovara - prazan spisak kofa trazi stablo
+Pogled na bazu se pravi pri prvom zahtevu i isti je do kraja veze*/
+type diffRequest struct {
+	Buckets []int
+}
+
+type diffResponse struct {
+	Tree    [][]byte
+	Buckets [][]KeyHash
+	Err     string
+}
+
+//Prihvata veze dok se listener ne zatvori, snapshot pravi pogled na bazu za svaku vezu
+func ServeDiff(listener net.Listener, snapshot func() (*StoreView, error)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go serveDiffConn(conn, snapshot)
+	}
+}
+
+func serveDiffConn(conn net.Conn, snapshot func() (*StoreView, error)) {
+	defer conn.Close()
+	decoder, encoder := gob.NewDecoder(conn), gob.NewEncoder(conn)
+	var view *StoreView
+	for {
+		request := diffRequest{}
+		if decoder.Decode(&request) != nil {
+			return
+		}
+		response := diffResponse{}
+		var err error
+		if view == nil {
+			view, err = snapshot()
+		}
+		if err == nil && len(request.Buckets) == 0 {
+			response.Tree, err = view.Tree()
+		} else if err == nil {
+			response.Buckets, err = view.Buckets(request.Buckets)
+		}
+		if err != nil {
+			view = nil
+			response.Err = err.Error()
+		}
+		if encoder.Encode(&response) != nil {
+			return
+		}
+	}
+}
+
+//Instanca koja radi i izlaze svoj pogled na lokalnom socket-u
+type RemoteStore struct {
+	path    string
+	conn    net.Conn
+	encoder *gob.Encoder
+	decoder *gob.Decoder
+}
+
+func DialStore(path string) (*RemoteStore, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteStore{path: path, conn: conn, encoder: gob.NewEncoder(conn), decoder: gob.NewDecoder(conn)}, nil
+}
+
+func (remote *RemoteStore) request(buckets []int) (*diffResponse, error) {
+	if err := remote.encoder.Encode(&diffRequest{Buckets: buckets}); err != nil {
+		return nil, err
+	}
+	response := &diffResponse{}
+	if err := remote.decoder.Decode(response); err != nil {
+		return nil, err
+	}
+	if response.Err != "" {
+		return nil, errors.New(remote.path + ": " + response.Err)
+	}
+	return response, nil
+}
+
+func (remote *RemoteStore) Tree() ([][]byte, error) {
+	response, err := remote.request(nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Tree, nil
+}
+
+func (remote *RemoteStore) Buckets(buckets []int) ([][]KeyHash, error) {
+	response, err := remote.request(buckets)
+	if err != nil {
+		return nil, err
+	}
+	return response.Buckets, nil
+}
+
+func (remote *RemoteStore) Close() error {
+	return remote.conn.Close()
+}
@@ -0,0 +1,239 @@
+package SSTable
+
+import (
+	"container/list"
+	"io"
+	"kv/bloom"
+	"kv/filter"
+	"kv/index"
+	"kv/summary"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Otvorena SSTabela - ucitan filter i summary i otvoreni index i data fajl
+//id je jedinstven za svako otvaranje tabele, pa se ne ponavlja ni kada kompakcija preimenuje tabelu
+//refs broji korisnike tabele (pretrage i iteratore), izbacena tabela se zatvara tek kada je svi oslobode
+type table struct {
+	id       uint64
+	name     string
+	filter   filter.Filter
+	prefixes *bloom.BloomFilter //prefiksni filter, nil ako ne postoji ili je napravljen drugim ekstraktorom
+	summary  *summary.Summary
+	index    *os.File
+	data     *os.File
+	dataSize int64
+	size     int64
+	refs     int
+	evicted  bool
+}
+
+func (t *table) close() {
+	t.index.Close()
+	t.data.Close()
+	blocks.dropTable(t.id)
+}
+
+//Citac data fajla preko blok kesa
+func (t *table) reader() io.ReaderAt {
+	return blockReader{t: t}
+}
+
+//LRU kes otvorenih SSTabela, ogranicen brojem tabela i zbirom velicina filtera i summary-ja
+type tableCache struct {
+	mutex     sync.Mutex
+	tables    map[string]*list.Element
+	order     *list.List
+	maxTables int
+	maxBytes  int64
+	bytes     int64
+	nextID    uint64
+}
+
+var tables = &tableCache{
+	tables:    make(map[string]*list.Element),
+	order:     list.New(),
+	maxTables: 16,
+	maxBytes:  8 << 20,
+}
+
+//Inicijalizuje ogranicenja kesa tabela iz eksterne konfiguracije
+//maxTables je max broj otvorenih tabela, maxBytes max zbir velicina ucitanih filtera i summary-ja
+func InitializeTableCacheConfigs(maxTables int, maxBytes int64) {
+	tables.mutex.Lock()
+	defer tables.mutex.Unlock()
+	tables.maxTables = maxTables
+	tables.maxBytes = maxBytes
+	tables.evict()
+}
+
+//Vraca otvorenu tabelu iz kesa, ili je otvara i ubacuje u kes
+//Tabela se posle upotrebe mora osloboditi pozivom release
+func (cache *tableCache) get(name string) (*table, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, found := cache.tables[name]; found {
+		cache.order.MoveToBack(element)
+		t := element.Value.(*table)
+		t.refs++
+		return t, nil
+	}
+
+	t, err := openTable(name)
+	if err != nil {
+		return nil, err
+	}
+	cache.nextID++
+	t.id = cache.nextID
+	t.refs = 1
+	cache.tables[name] = cache.order.PushBack(t)
+	cache.bytes += t.size
+	cache.evict()
+	return t, nil
+}
+
+//Izbacuje najdavnije koriscene tabele dok kes ne bude u okviru ogranicenja
+//Poslednja ubacena tabela ostaje i kada je sama veca od ogranicenja
+func (cache *tableCache) evict() {
+	for cache.order.Len() > 1 && (cache.order.Len() > cache.maxTables || cache.bytes > cache.maxBytes) {
+		cache.remove(cache.order.Front())
+	}
+}
+
+func (cache *tableCache) remove(element *list.Element) {
+	t := element.Value.(*table)
+	cache.order.Remove(element)
+	delete(cache.tables, t.name)
+	cache.bytes -= t.size
+	t.evicted = true
+	if t.refs == 0 {
+		t.close()
+	}
+}
+
+//Oslobadja tabelu dobijenu od get
+func (cache *tableCache) release(t *table) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	t.refs--
+	if t.evicted && t.refs == 0 {
+		t.close()
+	}
+}
+
+func openTable(name string) (*table, error) {
+	f, err := filter.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	prefixes, spec, err := bloom.LoadPrefixBloom(name)
+	if err != nil {
+		return nil, err
+	}
+	if prefixes != nil && spec != prefixExtractor.String() {
+		prefixes = nil
+	}
+	sum, err := summary.LoadSummary(name)
+	if err != nil {
+		return nil, err
+	}
+	indexFile, err := os.Open("data/SSTable" + name + "/index" + name + ".txt")
+	if err != nil {
+		return nil, err
+	}
+	dataFile, err := os.Open("data/SSTable" + name + "/SSTable" + name + ".txt")
+	if err != nil {
+		indexFile.Close()
+		return nil, err
+	}
+	dataSize, err := fileSize(dataFile)
+	if err != nil {
+		indexFile.Close()
+		dataFile.Close()
+		return nil, err
+	}
+	size := int64(f.Size() + sum.Size())
+	if prefixes != nil {
+		size += int64(prefixes.Size())
+	}
+	return &table{name: name, filter: f, prefixes: prefixes, summary: sum, index: indexFile, data: dataFile, dataSize: dataSize, size: size}, nil
+}
+
+//Zatvara i izbacuje sve tabele iz kesa
+func (cache *tableCache) clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for cache.order.Len() > 0 {
+		cache.remove(cache.order.Front())
+	}
+}
+
+//Zatvara i izbacuje iz kesa tabelu sa zadatim imenom
+//Poziva se kada se tabela pravi, menja ili brise, jer se imena tabela ponovo koriste
+func InvalidateTable(name string) {
+	tables.mutex.Lock()
+	defer tables.mutex.Unlock()
+	if element, found := tables.tables[name]; found {
+		tables.remove(element)
+	}
+}
+
+//Zatvara i izbacuje iz kesa sve tabele jednog nivoa - kompakcija brise i preimenuje tabele celog nivoa
+func InvalidateLevel(level int) {
+	tables.mutex.Lock()
+	defer tables.mutex.Unlock()
+	prefix := strconv.Itoa(level) + "_"
+	for name, element := range tables.tables {
+		if strings.HasPrefix(name, prefix) {
+			tables.remove(element)
+		}
+	}
+}
+
+//Sekvencijalno citanje zapisa jedne SSTabele preko blok kesa
+type TableIterator struct {
+	t      *table
+	offset uint64
+}
+
+func NewTableIterator(name string) (*TableIterator, error) {
+	t, err := tables.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &TableIterator{t: t}, nil
+}
+
+//Vraca sledeci zapis (zaglavlje, kljuc i vrednost), a na kraju tabele io.EOF
+func (it *TableIterator) Next() ([]byte, error) {
+	if it.offset >= uint64(it.t.dataSize) {
+		return nil, io.EOF
+	}
+	record, err := readRecordAt(it.t.reader(), it.t.data.Name(), it.t.dataSize, it.offset)
+	if err != nil {
+		return nil, err
+	}
+	it.offset += uint64(len(record))
+	return record, nil
+}
+
+//Pozicionira iterator na prvi zapis sa kljucem vecim ili jednakim zadatom
+func (it *TableIterator) Seek(key string) error {
+	indexOffset, found := it.t.summary.Seek(key)
+	if !found {
+		it.offset = uint64(it.t.dataSize)
+		return nil
+	}
+	offset, err := index.FindIn(it.t.index, indexOffset)
+	if err != nil {
+		return err
+	}
+	it.offset = offset
+	return nil
+}
+
+func (it *TableIterator) Close() {
+	tables.release(it.t)
+}
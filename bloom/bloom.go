@@ -1,113 +1,187 @@
 package bloom
 
 import (
-	"bufio"
+	"encoding/binary"
+	"errors"
 	"github.com/spaolacci/murmur3"
 	"hash"
-	"log"
 	"math"
-	"os"
-	"strconv"
 	"time"
 )
 
+//Bloom filter sa bitovima spakovanim u niz uint64
+//Pozicije se racunaju double hashing-om: g_i(x) = h1(x) + i*h2(x) mod m,
+//gde su h1 i h2 dve polovine 128-bitnog murmur3 digesta kljuca
+type BloomFilter struct {
+	m     uint     //broj bitova
+	k     uint     //broj hash funkcija
+	seeds []uint32 //seme za murmur3, upisuje se u zaglavlje
+	bits  []uint64
+}
+
+//Zaglavlje na disku: m 8B, k 4B, broj semena 4B, pa seme po 4B
+const headerSize = 16
+
+var ErrCorrupted = errors.New("bloom filter corrupted")
+
+//Filteri se mogu spojiti samo ako imaju isti broj bitova, hash funkcija i seme
+var ErrIncompatible = errors.New("bloom filters are incompatible")
+
+//Pravi prazan filter za ocekivani broj elemenata i zeljenu stopu laznih pozitiva
+//Namenjen je aplikaciji, za razliku od NewBloom nema donju granicu broja elemenata
+func New(expectedElements int, falsePositiveRate float64) *BloomFilter {
+	if expectedElements < 1 {
+		expectedElements = 1
+	}
+	m := CalculateM(expectedElements, falsePositiveRate)
+	k := CalculateK(expectedElements, m)
+	_, seeds := CreateHashFunctions(1)
+	return &BloomFilter{m: m, k: k, seeds: seeds, bits: make([]uint64, (m+63)/64)}
+}
+
 //Put funkcija
 //prosledjuje se niz kljuceva od kojih se formira bloom filter, ocekivani broj elemenata(max) i "tacnost"
-func NewBloom(keys []string, falsePositiveRate float64) (string, []uint32) {
+func NewBloom(keys []string, falsePositiveRate float64) *BloomFilter {
 	expectedElements := len(keys)
 	if expectedElements < 100 {
 		expectedElements = 100
 	}
-	m := CalculateM(expectedElements, falsePositiveRate)
-	k := CalculateK(expectedElements, m)
-	bloom := ""
-	for i := 0; i < int(m); i++ {
-		bloom = bloom + "0"
-	}
-	hashes, seeds := CreateHashFunctions(k)
+	bloom := New(expectedElements, falsePositiveRate)
 
 	for _, key := range keys {
-		bloom = AddKey(bloom, key, hashes)
+		bloom.AddKey(key)
 	}
-	return bloom, seeds
+	return bloom
 }
 
-func WriteBloom(bloom string, seeds []uint32, name string) {
-	file, err := os.OpenFile("data/SSTable"+name+"/filter"+name+".txt", os.O_WRONLY, 0666)
-	if err != nil {
-		panic(err)
+//Pozicije bitova za kljuc
+func (bloom *BloomFilter) positions(key string) []uint {
+	h1, h2 := murmur3.Sum128WithSeed([]byte(key), bloom.seeds[0])
+	h2 |= 1 //neparan korak, kako bi sve pozicije bile razlicite kada je m stepen dvojke
+	positions := make([]uint, bloom.k)
+	for i := uint(0); i < bloom.k; i++ {
+		positions[i] = uint((h1 + uint64(i)*h2) % uint64(bloom.m))
 	}
-	defer file.Close()
-	file.Write([]byte(bloom))
-	file.WriteString("\n")
-	//Write seeds
-	for _, seed := range seeds {
-		file.WriteString(strconv.Itoa(int(seed)) + " ")
+	return positions
+}
+
+//Funkcija se koristi u NewBloom, ali ako je potrebno uneti samo jedan kljuc moze biti korisna
+func (bloom *BloomFilter) AddKey(key string) {
+	for _, position := range bloom.positions(key) {
+		bloom.bits[position/64] |= 1 << (position % 64)
 	}
 }
 
-func LoadBool(name string) (string, []uint32) {
-	file, err := os.Open("data/SSTable" + name + "/filter" + name + ".txt")
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanWords)
-	//First scan for bool filter
-	success := scanner.Scan()
-	if success == false {
-		err = scanner.Err()
-		if err == nil {
-		} else {
-			log.Fatal(err)
-		}
+//Add i MayContain su AddKey i IsInBloom pod imenima interfejsa filter.Filter
+//Bloom filter se nikad ne puni, pa Add uvek uspeva
+func (bloom *BloomFilter) Add(key string) bool {
+	bloom.AddKey(key)
+	return true
+}
+
+func (bloom *BloomFilter) MayContain(key string) bool {
+	return bloom.IsInBloom(key)
+}
+
+//Contains je IsInBloom za aplikacioni kod - true znaci "mozda postoji", false "sigurno ne postoji"
+func (bloom *BloomFilter) Contains(key string) bool {
+	return bloom.IsInBloom(key)
+}
+
+//Dodaje u filter sve elemente drugog filtera (OR bitova)
+func (bloom *BloomFilter) Union(other *BloomFilter) error {
+	if bloom.m != other.m || bloom.k != other.k || len(bloom.seeds) != len(other.seeds) {
+		return ErrIncompatible
 	}
-	bloom := scanner.Text()
-	//x scans for hash seeds
-	seeds := make([]uint32, 0)
-	for {
-		success := scanner.Scan()
-		if success == false {
-			err = scanner.Err()
-			if err == nil {
-				break
-			} else {
-				log.Fatal(err)
-			}
+	for i := range bloom.seeds {
+		if bloom.seeds[i] != other.seeds[i] {
+			return ErrIncompatible
 		}
-		temp, _ := strconv.Atoi(scanner.Text())
-		seeds = append(seeds, uint32(temp))
 	}
-	return bloom, seeds
+	for i := range bloom.bits {
+		bloom.bits[i] |= other.bits[i]
+	}
+	return nil
 }
 
-//Funkcija se koristi u NewBloom, ali ako je potrebno uneti samo jedan kljuc moze biti korisna
-func AddKey(bloom string, key string, hashes []hash.Hash32) string {
-	for _, ha := range hashes {
-		hashed := ha.Sum([]byte(key))
-		for _, h := range hashed {
-			bloom = bloom[:int(h)] + "1" + bloom[int(h)+1:]
-		}
-	}
-	return bloom
+//Pravi prazan filter sa istim parametrima i semenom, kako bi se kasnije mogao spojiti sa ovim
+func (bloom *BloomFilter) Compatible() *BloomFilter {
+	seeds := make([]uint32, len(bloom.seeds))
+	copy(seeds, bloom.seeds)
+	return &BloomFilter{m: bloom.m, k: bloom.k, seeds: seeds, bits: make([]uint64, len(bloom.bits))}
 }
 
 //Get funkcija proverava postojanje kljuca u bloom filteru
-//potrebno proslediti bloom filter, kljuc koji se trazi i hash funkcije generisane "CreateHashFunctions"metodom
-func IsInBloom(bloom string, key string, seeds []uint32) bool {
-	hashes := CreateHashFunctionsFromSeeds(seeds)
-	for _, ha := range hashes {
-		hashed := ha.Sum([]byte(key))
-		for _, h := range hashed {
-			if bloom[int(h)] != '1' {
-				return false
-			}
+func (bloom *BloomFilter) IsInBloom(key string) bool {
+	for _, position := range bloom.positions(key) {
+		if bloom.bits[position/64]&(1<<(position%64)) == 0 {
+			return false
 		}
 	}
 	return true
 }
 
+//Velicina filtera u memoriji u bajtovima
+func (bloom *BloomFilter) Size() int {
+	return 8*len(bloom.bits) + 4*len(bloom.seeds)
+}
+
+//Binarni zapis filtera: zaglavlje (m, k, seme), pa bitovi kao uint64 little endian
+func (bloom *BloomFilter) Marshal() []byte {
+	bytes := make([]byte, headerSize+4*len(bloom.seeds)+8*len(bloom.bits))
+	binary.LittleEndian.PutUint64(bytes[0:8], uint64(bloom.m))
+	binary.LittleEndian.PutUint32(bytes[8:12], uint32(bloom.k))
+	binary.LittleEndian.PutUint32(bytes[12:16], uint32(len(bloom.seeds)))
+	offset := headerSize
+	for _, seed := range bloom.seeds {
+		binary.LittleEndian.PutUint32(bytes[offset:], seed)
+		offset += 4
+	}
+	for _, word := range bloom.bits {
+		binary.LittleEndian.PutUint64(bytes[offset:], word)
+		offset += 8
+	}
+	return bytes
+}
+
+func Unmarshal(bytes []byte) (*BloomFilter, error) {
+	if len(bytes) < headerSize {
+		return nil, ErrCorrupted
+	}
+	m := binary.LittleEndian.Uint64(bytes[0:8])
+	k := binary.LittleEndian.Uint32(bytes[8:12])
+	seedCount := uint64(binary.LittleEndian.Uint32(bytes[12:16]))
+	//Bitovi moraju stati u zapis - provera pre racunanja broja reci, jer (m+63)/64 prekoraci za m blizu 2^64
+	if m > 8*uint64(len(bytes)) || uint64(k) > m {
+		return nil, ErrCorrupted
+	}
+	words := (m + 63) / 64
+	if m == 0 || k == 0 || seedCount == 0 || uint64(len(bytes)) != headerSize+4*seedCount+8*words {
+		return nil, ErrCorrupted
+	}
+	bloom := &BloomFilter{m: uint(m), k: uint(k), seeds: make([]uint32, seedCount), bits: make([]uint64, words)}
+	offset := headerSize
+	for i := range bloom.seeds {
+		bloom.seeds[i] = binary.LittleEndian.Uint32(bytes[offset:])
+		offset += 4
+	}
+	for i := range bloom.bits {
+		bloom.bits[i] = binary.LittleEndian.Uint64(bytes[offset:])
+		offset += 8
+	}
+	return bloom, nil
+}
+
+//Ucitava zapis u postojecu vrednost (interfejs filter.Filter)
+func (bloom *BloomFilter) Unmarshal(bytes []byte) error {
+	loaded, err := Unmarshal(bytes)
+	if err != nil {
+		return err
+	}
+	*bloom = *loaded
+	return nil
+}
+
 //Pomocne metode
 func CalculateM(expectedElements int, falsePositiveRate float64) uint {
 	return uint(math.Ceil(float64(expectedElements) * math.Abs(math.Log(falsePositiveRate)) / math.Pow(math.Log(2), float64(2))))
@@ -128,11 +202,3 @@ func CreateHashFunctions(k uint) ([]hash.Hash32, []uint32) {
 	}
 	return h, seed
 }
-
-func CreateHashFunctionsFromSeeds(seeds []uint32) []hash.Hash32 {
-	h := make([]hash.Hash32, 0)
-	for _, seed := range seeds {
-		h = append(h, murmur3.New32WithSeed(seed))
-	}
-	return h
-}
@@ -0,0 +1,65 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"strconv"
+	"testing"
+)
+
+func TestNoFalseNegatives(t *testing.T) {
+	bloom := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		bloom.AddKey("kljuc" + strconv.Itoa(i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !bloom.IsInBloom("kljuc" + strconv.Itoa(i)) {
+			t.Fatalf("kljuc%d nije pronadjen", i)
+		}
+	}
+}
+
+//Stopa laznih pozitiva za kljuceve koji nisu dodati mora biti blizu trazene
+func TestFalsePositiveRate(t *testing.T) {
+	for _, rate := range []float64{0.1, 0.01, 0.001} {
+		const expected, probes = 10000, 100000
+		bloom := New(expected, rate)
+		for i := 0; i < expected; i++ {
+			bloom.AddKey("dodat" + strconv.Itoa(i))
+		}
+		falsePositives := 0
+		for i := 0; i < probes; i++ {
+			if bloom.IsInBloom("nije" + strconv.Itoa(i)) {
+				falsePositives++
+			}
+		}
+		measured := float64(falsePositives) / probes
+		if measured > 2*rate {
+			t.Errorf("trazeno %g, izmereno %g", rate, measured)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	bloom := New(100, 0.01)
+	bloom.AddKey("a")
+	loaded, err := Unmarshal(bloom.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsInBloom("a") {
+		t.Fatal("kljuc a nije pronadjen posle ucitavanja")
+	}
+}
+
+//m blizu 2^64 ne sme proci proveru duzine zbog prekoracenja (m+63)/64
+func TestUnmarshalOversizedHeader(t *testing.T) {
+	for _, m := range []uint64{1<<64 - 1, 1<<64 - 63, 1 << 40} {
+		bytes := make([]byte, headerSize+4)
+		binary.LittleEndian.PutUint64(bytes[0:8], m)
+		binary.LittleEndian.PutUint32(bytes[8:12], 3)
+		binary.LittleEndian.PutUint32(bytes[12:16], 1)
+		if _, err := Unmarshal(bytes); err != ErrCorrupted {
+			t.Errorf("m=%d: ocekivano %v, dobijeno %v", m, ErrCorrupted, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Izdvaja prefiks kljuca za prefiksni bloom filter
+//fixed:N - prvih N bajtova kljuca, delim:D - kljuc do prvog pojavljivanja D (zajedno sa D)
+//Kljucevi iz kojih se prefiks ne moze izdvojiti (kraci od N ili bez D) ne ulaze u filter
+type PrefixExtractor struct {
+	length    int
+	delimiter string
+}
+
+//Parsira opis ekstraktora iz konfiguracije, za "" i "none" vraca nil (prefiksni filteri iskljuceni)
+func ParsePrefixExtractor(spec string) (*PrefixExtractor, error) {
+	switch {
+	case spec == "" || spec == "none":
+		return nil, nil
+	case strings.HasPrefix(spec, "fixed:"):
+		length, err := strconv.Atoi(strings.TrimPrefix(spec, "fixed:"))
+		if err != nil || length < 1 || length > 255 {
+			return nil, fmt.Errorf("invalid prefix length in %q", spec)
+		}
+		return &PrefixExtractor{length: length}, nil
+	case strings.HasPrefix(spec, "delim:"):
+		delimiter := strings.TrimPrefix(spec, "delim:")
+		if delimiter == "" || len(delimiter) > 16 {
+			return nil, fmt.Errorf("invalid prefix delimiter in %q", spec)
+		}
+		return &PrefixExtractor{delimiter: delimiter}, nil
+	}
+	return nil, fmt.Errorf("unknown prefix extractor %q (fixed:N, delim:D or none)", spec)
+}
+
+func (extractor *PrefixExtractor) String() string {
+	if extractor == nil {
+		return "none"
+	}
+	if extractor.delimiter != "" {
+		return "delim:" + extractor.delimiter
+	}
+	return "fixed:" + strconv.Itoa(extractor.length)
+}
+
+//Vraca prefiks kljuca i da li ga kljuc ima
+//Isto vazi i za prefiks pretrage: ako se iz njega izdvoji p, svaki kljuc koji pocinje njime ima prefiks p
+func (extractor *PrefixExtractor) Extract(key string) (string, bool) {
+	if extractor.delimiter != "" {
+		i := strings.Index(key, extractor.delimiter)
+		if i < 0 {
+			return "", false
+		}
+		return key[:i+len(extractor.delimiter)], true
+	}
+	if len(key) < extractor.length {
+		return "", false
+	}
+	return key[:extractor.length], true
+}
+
+//Pravi filter nad razlicitim prefiksima kljuceva
+func NewPrefixBloom(keys []string, extractor *PrefixExtractor, falsePositiveRate float64) *BloomFilter {
+	prefixes := make([]string, 0)
+	last := ""
+	for _, key := range keys {
+		prefix, ok := extractor.Extract(key)
+		//Kljucevi su sortirani, pa su isti prefiksi jedan do drugog
+		if ok && (len(prefixes) == 0 || prefix != last) {
+			prefixes = append(prefixes, prefix)
+			last = prefix
+		}
+	}
+	return NewBloom(prefixes, falsePositiveRate)
+}
+
+//Fajl prefiksnog filtera: duzina opisa ekstraktora 1B, opis, pa filter
+//Opis se cuva kako se filter ne bi koristio nakon promene konfiguracije
+func WritePrefixBloom(bloom *BloomFilter, extractor *PrefixExtractor, name string) error {
+	spec := extractor.String()
+	bytes := make([]byte, 0, 1+len(spec)+headerSize)
+	bytes = append(bytes, byte(len(spec)))
+	bytes = append(bytes, spec...)
+	bytes = append(bytes, bloom.Marshal()...)
+	return ioutil.WriteFile(prefixPath(name), bytes, 0666)
+}
+
+//Ucitava prefiksni filter tabele i opis ekstraktora kojim je napravljen
+//Tabele napravljene bez prefiksnog filtera vracaju nil bez greske
+func LoadPrefixBloom(name string) (*BloomFilter, string, error) {
+	bytes, err := ioutil.ReadFile(prefixPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(bytes) < 1 || len(bytes) < 1+int(bytes[0]) {
+		return nil, "", fmt.Errorf("prefix filter of SSTable%s: %w", name, ErrCorrupted)
+	}
+	spec := string(bytes[1 : 1+int(bytes[0])])
+	bloom, err := Unmarshal(bytes[1+int(bytes[0]):])
+	if err != nil {
+		return nil, "", fmt.Errorf("prefix filter of SSTable%s: %w", name, err)
+	}
+	return bloom, spec, nil
+}
+
+func RemovePrefixBloom(name string) error {
+	err := os.Remove(prefixPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func prefixPath(name string) string {
+	return "data/SSTable" + name + "/prefix" + name + ".txt"
+}
@@ -0,0 +1,114 @@
+package main
+
+import "container/list"
+
+/*2Q (Johnson i Shasha) - nove stavke ulaze u FIFO a1in, a tek ponovljen pristup ih dovodi u LRU am
+Kljucevi izbacenih iz a1in se pamte u a1out (bez vrednosti), pa stavka koja se vrati posle izbacivanja ide pravo u am
+Jednokratni pristupi (skeniranja) zato prolaze kroz a1in i ne izbacuju vruce stavke iz am*/
+type twoQueuePolicy struct {
+	max_bytes int
+	kin       int //max velicina a1in
+	kout      int //max zbir velicina stavki ciji se kljucevi pamte u a1out
+	items     map[string]*list.Element
+	ghosts    map[string]*list.Element
+	a1in      *cacheSegment
+	a1out     *cacheSegment
+	am        *cacheSegment
+}
+
+func newTwoQueuePolicy(max_bytes int) *twoQueuePolicy {
+	return &twoQueuePolicy{
+		max_bytes: max_bytes,
+		kin:       max_bytes / 4,
+		kout:      max_bytes / 2,
+		items:     make(map[string]*list.Element),
+		ghosts:    make(map[string]*list.Element),
+		a1in:      newCacheSegment(),
+		a1out:     newCacheSegment(),
+		am:        newCacheSegment(),
+	}
+}
+
+func (policy *twoQueuePolicy) Get(key string) ([]byte, bool) {
+	element, is_present := policy.items[key]
+	if !is_present {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	//Pristup stavci u a1in ne menja njen polozaj - FIFO
+	if entry.segment == policy.am {
+		policy.am.list.MoveToFront(element)
+	}
+	return entry.value, true
+}
+
+func (policy *twoQueuePolicy) Put(key string, value []byte) int {
+	if element, is_present := policy.items[key]; is_present {
+		entry := element.Value.(*cacheEntry)
+		if entrySize(key, value) > policy.max_bytes {
+			policy.Remove(key)
+			return 0
+		}
+		entry.segment.update(element, value)
+		if entry.segment == policy.am {
+			policy.am.list.MoveToFront(element)
+		}
+		return policy.reclaim()
+	}
+	entry := newCacheEntry(key, value)
+	if entry.size > policy.max_bytes {
+		return 0
+	}
+	if ghost, is_present := policy.ghosts[key]; is_present {
+		policy.a1out.remove(ghost)
+		delete(policy.ghosts, key)
+		policy.items[key] = policy.am.pushFront(entry)
+	} else {
+		policy.items[key] = policy.a1in.pushFront(entry)
+	}
+	return policy.reclaim()
+}
+
+//Izbacuje stavke dok ukupna velicina ne padne na kapacitet
+func (policy *twoQueuePolicy) reclaim() int {
+	evicted := 0
+	for policy.a1in.bytes+policy.am.bytes > policy.max_bytes {
+		if policy.a1in.bytes > policy.kin || policy.am.list.Len() == 0 {
+			entry := policy.a1in.remove(policy.a1in.list.Back())
+			delete(policy.items, entry.key)
+			entry.value = nil
+			policy.ghosts[entry.key] = policy.a1out.pushFront(entry)
+			for policy.a1out.bytes > policy.kout {
+				delete(policy.ghosts, policy.a1out.remove(policy.a1out.list.Back()).key)
+			}
+		} else {
+			delete(policy.items, policy.am.remove(policy.am.list.Back()).key)
+		}
+		evicted++
+	}
+	return evicted
+}
+
+func (policy *twoQueuePolicy) Remove(key string) {
+	if element, is_present := policy.items[key]; is_present {
+		element.Value.(*cacheEntry).segment.remove(element)
+		delete(policy.items, key)
+	}
+}
+
+func (policy *twoQueuePolicy) Contains(key string) bool {
+	_, is_present := policy.items[key]
+	return is_present
+}
+
+func (policy *twoQueuePolicy) Len() int {
+	return len(policy.items)
+}
+
+func (policy *twoQueuePolicy) Bytes() int {
+	return policy.a1in.bytes + policy.am.bytes
+}
+
+func (policy *twoQueuePolicy) Keys() []string {
+	return segmentKeys(policy.am, policy.a1in)
+}
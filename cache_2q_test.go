@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+//Pristup stavci u a1in je ne unapredjuje, a kljuc koji se vrati posle izbacivanja iz a1in ide u am
+func TestTwoQueuePolicyPromotesGhost(t *testing.T) {
+	policy := newTwoQueuePolicy(1000)
+	policy.Put("a", cacheValue("a", 100))
+	policy.Get("a")
+	if segment := policy.items["a"].Value.(*cacheEntry).segment; segment != policy.a1in {
+		t.Fatal("ocekivano da a ostane u a1in posle pristupa")
+	}
+	for i := 0; i < 10; i++ {
+		policy.Put("s"+strconv.Itoa(i), cacheValue("s"+strconv.Itoa(i), 100))
+	}
+	if policy.Contains("a") {
+		t.Fatal("ocekivano da je a izbacen iz a1in")
+	}
+	if _, is_ghost := policy.ghosts["a"]; !is_ghost {
+		t.Fatal("ocekivano da se a pamti u a1out")
+	}
+	policy.Put("a", cacheValue("a", 100))
+	if segment := policy.items["a"].Value.(*cacheEntry).segment; segment != policy.am {
+		t.Fatal("ocekivano da a predje u am")
+	}
+}
+
+//Skeniranje jednokratnih kljuceva ne izbacuje stavke iz am
+func TestTwoQueuePolicyScanResistance(t *testing.T) {
+	policy := newTwoQueuePolicy(1000)
+	hot := []string{"h0", "h1", "h2"}
+	for _, key := range hot {
+		policy.Put(key, cacheValue(key, 100))
+	}
+	for i := 0; i < 10; i++ {
+		policy.Put("f"+strconv.Itoa(i), cacheValue("f"+strconv.Itoa(i), 100))
+	}
+	for _, key := range hot {
+		policy.Put(key, cacheValue(key, 100))
+	}
+	for i := 0; i < 100; i++ {
+		policy.Put("s"+strconv.Itoa(i), cacheValue("s"+strconv.Itoa(i), 99))
+	}
+	for _, key := range hot {
+		if !policy.Contains(key) {
+			t.Fatalf("skeniranje je izbacilo %s", key)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import "container/list"
+
+/*ARC (Megiddo i Modha) - t1 sadrzi stavke vidjene jednom, t2 stavke vidjene bar dva puta
+b1 i b2 pamte kljuceve izbacene iz t1 i t2, a cilj p (velicina t1 u bajtovima) se prilagodjava:
+pogodak u b1 znaci da je t1 premali, pogodak u b2 da je t2 premali
+Velicine su u bajtovima, pa se p pomera za velicinu stavke umesto za 1*/
+type arcPolicy struct {
+	max_bytes int
+	p         int
+	items     map[string]*list.Element
+	ghosts    map[string]*list.Element
+	t1        *cacheSegment
+	t2        *cacheSegment
+	b1        *cacheSegment
+	b2        *cacheSegment
+}
+
+func newARCPolicy(max_bytes int) *arcPolicy {
+	return &arcPolicy{
+		max_bytes: max_bytes,
+		items:     make(map[string]*list.Element),
+		ghosts:    make(map[string]*list.Element),
+		t1:        newCacheSegment(),
+		t2:        newCacheSegment(),
+		b1:        newCacheSegment(),
+		b2:        newCacheSegment(),
+	}
+}
+
+func (policy *arcPolicy) Get(key string) ([]byte, bool) {
+	element, is_present := policy.items[key]
+	if !is_present {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	policy.items[key] = entry.segment.moveTo(element, policy.t2)
+	return entry.value, true
+}
+
+func (policy *arcPolicy) Put(key string, value []byte) int {
+	size := entrySize(key, value)
+	if size > policy.max_bytes {
+		policy.Remove(key)
+		return 0
+	}
+	if element, is_present := policy.items[key]; is_present {
+		entry := element.Value.(*cacheEntry)
+		entry.segment.update(element, value)
+		element = entry.segment.moveTo(element, policy.t2)
+		policy.items[key] = element
+		return policy.replaceUntilFits(false, element)
+	}
+
+	if ghost, is_present := policy.ghosts[key]; is_present {
+		entry := ghost.Value.(*cacheEntry)
+		inB2 := entry.segment == policy.b2
+		if inB2 {
+			delta := size
+			if policy.b2.bytes > 0 && policy.b1.bytes > policy.b2.bytes {
+				delta = size * policy.b1.bytes / policy.b2.bytes
+			}
+			policy.p -= delta
+			if policy.p < 0 {
+				policy.p = 0
+			}
+		} else {
+			delta := size
+			if policy.b1.bytes > 0 && policy.b2.bytes > policy.b1.bytes {
+				delta = size * policy.b2.bytes / policy.b1.bytes
+			}
+			policy.p += delta
+			if policy.p > policy.max_bytes {
+				policy.p = policy.max_bytes
+			}
+		}
+		entry.segment.remove(ghost)
+		delete(policy.ghosts, key)
+		entry.value = value
+		entry.size = size
+		element := policy.t2.pushFront(entry)
+		policy.items[key] = element
+		evicted := policy.replaceUntilFits(inB2, element)
+		policy.trimGhosts()
+		return evicted
+	}
+
+	element := policy.t1.pushFront(newCacheEntry(key, value))
+	policy.items[key] = element
+	evicted := policy.replaceUntilFits(false, element)
+	policy.trimGhosts()
+	return evicted
+}
+
+//Izbacuje stavke iz t1 ili t2 (u b1 ili b2) dok t1 i t2 ne stanu u kapacitet
+//Upravo ubacena stavka (keep) se ne izbacuje
+func (policy *arcPolicy) replaceUntilFits(inB2 bool, keep *list.Element) int {
+	evicted := 0
+	for policy.t1.bytes+policy.t2.bytes > policy.max_bytes {
+		fromT1 := policy.t1.list.Len() > 0 &&
+			(policy.t1.bytes > policy.p || (inB2 && policy.t1.bytes == policy.p) || policy.t2.list.Len() == 0)
+		if fromT1 && policy.t1.list.Back() == keep {
+			fromT1 = false
+		}
+		if !fromT1 && (policy.t2.list.Len() == 0 || policy.t2.list.Back() == keep) {
+			fromT1 = true
+		}
+		if fromT1 {
+			policy.evict(policy.t1, policy.b1)
+		} else {
+			policy.evict(policy.t2, policy.b2)
+		}
+		evicted++
+	}
+	return evicted
+}
+
+func (policy *arcPolicy) evict(from *cacheSegment, ghost *cacheSegment) {
+	entry := from.remove(from.list.Back())
+	delete(policy.items, entry.key)
+	entry.value = nil
+	policy.ghosts[entry.key] = ghost.pushFront(entry)
+}
+
+//Kljucevi u b1 i b2 zajedno sa stavkama ne smeju preci dvostruki kapacitet, a t1 i b1 kapacitet
+func (policy *arcPolicy) trimGhosts() {
+	for policy.t1.bytes+policy.b1.bytes > policy.max_bytes && policy.b1.list.Len() > 0 {
+		delete(policy.ghosts, policy.b1.remove(policy.b1.list.Back()).key)
+	}
+	for policy.t1.bytes+policy.t2.bytes+policy.b1.bytes+policy.b2.bytes > 2*policy.max_bytes && policy.b2.list.Len() > 0 {
+		delete(policy.ghosts, policy.b2.remove(policy.b2.list.Back()).key)
+	}
+}
+
+func (policy *arcPolicy) Remove(key string) {
+	if element, is_present := policy.items[key]; is_present {
+		element.Value.(*cacheEntry).segment.remove(element)
+		delete(policy.items, key)
+	}
+}
+
+func (policy *arcPolicy) Contains(key string) bool {
+	_, is_present := policy.items[key]
+	return is_present
+}
+
+func (policy *arcPolicy) Len() int {
+	return len(policy.items)
+}
+
+func (policy *arcPolicy) Bytes() int {
+	return policy.t1.bytes + policy.t2.bytes
+}
+
+func (policy *arcPolicy) Keys() []string {
+	return segmentKeys(policy.t2, policy.t1)
+}
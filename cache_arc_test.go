@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+//Stavka pogodjena dva puta prelazi u t2 i ne izbacuje je skeniranje jednokratnih kljuceva
+func TestARCPolicyScanResistance(t *testing.T) {
+	policy := newARCPolicy(1000)
+	policy.Put("a", cacheValue("a", 100))
+	policy.Get("a")
+	if segment := policy.items["a"].Value.(*cacheEntry).segment; segment != policy.t2 {
+		t.Fatal("ocekivano da a predje u t2")
+	}
+	for i := 0; i < 20; i++ {
+		policy.Put("s"+strconv.Itoa(i), cacheValue("s"+strconv.Itoa(i), 100))
+	}
+	if !policy.Contains("a") {
+		t.Fatal("skeniranje je izbacilo a")
+	}
+	if policy.t1.bytes+policy.b1.bytes > policy.max_bytes {
+		t.Fatalf("t1 i b1 imaju %d bajtova, vise od kapaciteta", policy.t1.bytes+policy.b1.bytes)
+	}
+}
+
+//Pogodak u b1 povecava cilj p i vraca kljuc u t2, a pogodak u b2 smanjuje p
+func TestARCPolicyAdaptsTarget(t *testing.T) {
+	policy := newARCPolicy(1000)
+	//Bez stavki u t2, t1 zauzima ceo kapacitet i b1 ostaje prazan
+	policy.Put("a", cacheValue("a", 100))
+	policy.Get("a")
+	for i := 0; i < 10; i++ {
+		policy.Put("s"+strconv.Itoa(i), cacheValue("s"+strconv.Itoa(i), 100))
+	}
+	ghost, is_ghost := policy.ghosts["s0"]
+	if !is_ghost || ghost.Value.(*cacheEntry).segment != policy.b1 {
+		t.Fatal("ocekivano da je s0 u b1")
+	}
+	policy.Put("s0", cacheValue("s0", 100))
+	if policy.p != 100 {
+		t.Fatalf("ocekivano p = 100, dobijeno %d", policy.p)
+	}
+	if segment := policy.items["s0"].Value.(*cacheEntry).segment; segment != policy.t2 {
+		t.Fatal("ocekivano da s0 predje u t2")
+	}
+
+	//Kljucevi iz t2 se izbacuju u b2 kada je t1 ispod cilja
+	for _, key := range policy.Keys() {
+		policy.Get(key)
+	}
+	policy.Put("n", cacheValue("n", 100))
+	var inB2 string
+	for key, ghost := range policy.ghosts {
+		if ghost.Value.(*cacheEntry).segment == policy.b2 {
+			inB2 = key
+		}
+	}
+	if inB2 == "" {
+		t.Fatal("ocekivan kljuc u b2")
+	}
+	p := policy.p
+	policy.Put(inB2, cacheValue(inB2, 100))
+	if policy.p >= p {
+		t.Fatalf("ocekivano da p opadne ispod %d, dobijeno %d", p, policy.p)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"kv/kompakcije"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+)
+
+//Nasumicni niz put, get, delete i kompakcija uporedjuje sa mapom
+//Seed-ovi su fiksni, pa se svako odstupanje moze ponoviti
+func TestCacheConsistency(t *testing.T) {
+	for _, seed := range []int64{1, 7, 42, 1234} {
+		t.Run("seed"+strconv.FormatInt(seed, 10), func(t *testing.T) {
+			checkCacheConsistency(t, seed, 3000)
+		})
+	}
+}
+
+//Sistem u privremenom direktorijumu sa podrazumevanom konfiguracijom, pa testovi ne diraju postojece podatke
+//Po zavrsetku testa zatvara WAL, vraca radni direktorijum i brise privremeni
+func newTestSystem(t *testing.T) *System {
+	chdirTemp(t)
+	if err := InitWAL(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+	sys := newSystem(Default())
+	sys.initializeConfigs(true)
+	return sys
+}
+
+//Prelazi u novi privremeni direktorijum sa praznim data/, koji se brise po zavrsetku testa
+func chdirTemp(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "kvtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	})
+	err = os.Chdir(dir)
+	if err == nil {
+		err = os.Mkdir("data", 0755)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkCacheConsistency(t *testing.T, seed int64, operations int) {
+	sys := newTestSystem(t)
+
+	random := rand.New(rand.NewSource(seed))
+	//Mali skup kljuceva, da bi se isti kljuc cesto menjao, brisao i citao iz razlicitih nivoa
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(i)
+	}
+	reference := make(map[string][]byte)
+	check := func(op int, key string) {
+		got := sys.get("", key)
+		expected, present := reference[key]
+		if (present && !bytes.Equal(got, expected)) || (!present && len(got) != 0) {
+			t.Fatalf("operacija %d: get(%s) = %q, ocekivano %q (postoji: %v)", op, key, got, expected, present)
+		}
+	}
+
+	for op := 1; op <= operations; op++ {
+		key := keys[random.Intn(len(keys))]
+		switch choice := random.Intn(100); {
+		case choice < 45:
+			value := make([]byte, 1+random.Intn(32))
+			random.Read(value)
+			if !sys.put("", key, value) {
+				t.Fatalf("operacija %d: put(%s) nije uspeo", op, key)
+			}
+			reference[key] = value
+		case choice < 85:
+			check(op, key)
+		case choice < 98:
+			sys.Delete("", key)
+			delete(reference, key)
+		default:
+			err := kompakcije.Kompakcija(sys.config.compaction_size, sys.config.max_height, sys.config.bloom_precision)
+			if err != nil {
+				t.Fatalf("operacija %d: %v", op, err)
+			}
+		}
+	}
+	for _, key := range keys {
+		check(operations, key)
+	}
+}
+
+//Obrisan kljuc se ne vraca posle kompakcije, ni kada starija verzija lezi na nizem nivou
+func TestDeleteSurvivesCompaction(t *testing.T) {
+	sys := newTestSystem(t)
+	compact := func() {
+		err := kompakcije.Kompakcija(sys.config.compaction_size, sys.config.max_height, sys.config.bloom_precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	//v1 zavrsava na drugom nivou, v2 na prvom
+	sys.put("", "k", []byte("v1"))
+	sys.put("", "x", []byte("1"))
+	sys.Flush()
+	sys.put("", "y", []byte("2"))
+	sys.Flush()
+	compact()
+	sys.put("", "k", []byte("v2"))
+	sys.Flush()
+	sys.put("", "z", []byte("3"))
+	sys.Flush()
+
+	sys.Delete("", "k")
+	compact()
+	compact()
+	if value := sys.get("", "k"); value != nil {
+		t.Fatalf("obrisan kljuc k vraca %q", value)
+	}
+	for key, expected := range map[string]string{"x": "1", "y": "2", "z": "3"} {
+		if value := string(sys.get("", key)); value != expected {
+			t.Errorf("get(%s) = %q, ocekivano %q", key, value, expected)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"container/heap"
+	"sort"
+)
+
+/*LFU - izbacuje se stavka sa najmanje pristupa, a medju njima ona kojoj se najduze nije pristupalo
+Stavke su u min heap-u po (broj pristupa, vreme poslednjeg pristupa)*/
+type lfuPolicy struct {
+	max_bytes int
+	bytes     int
+	tick      uint64
+	items     map[string]*cacheEntry
+	heap      lfuHeap
+}
+
+type lfuHeap []*cacheEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap) Push(x interface{}) {
+	entry := x.(*cacheEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+func newLFUPolicy(max_bytes int) *lfuPolicy {
+	return &lfuPolicy{max_bytes: max_bytes, items: make(map[string]*cacheEntry)}
+}
+
+func (policy *lfuPolicy) touch(entry *cacheEntry) {
+	policy.tick++
+	entry.freq++
+	entry.tick = policy.tick
+	heap.Fix(&policy.heap, entry.index)
+}
+
+func (policy *lfuPolicy) Get(key string) ([]byte, bool) {
+	entry, is_present := policy.items[key]
+	if !is_present {
+		return nil, false
+	}
+	policy.touch(entry)
+	return entry.value, true
+}
+
+func (policy *lfuPolicy) Put(key string, value []byte) int {
+	freq := uint64(0)
+	if entry, is_present := policy.items[key]; is_present {
+		//Izmena vrednosti zadrzava broj pristupa
+		freq = entry.freq
+		policy.Remove(key)
+	}
+	entry := newCacheEntry(key, value)
+	if entry.size > policy.max_bytes {
+		return 0
+	}
+	evicted := 0
+	for policy.bytes+entry.size > policy.max_bytes {
+		victim := heap.Pop(&policy.heap).(*cacheEntry)
+		delete(policy.items, victim.key)
+		policy.bytes -= victim.size
+		evicted++
+	}
+	policy.tick++
+	entry.freq = freq + 1
+	entry.tick = policy.tick
+	heap.Push(&policy.heap, entry)
+	policy.items[key] = entry
+	policy.bytes += entry.size
+	return evicted
+}
+
+func (policy *lfuPolicy) Remove(key string) {
+	if entry, is_present := policy.items[key]; is_present {
+		heap.Remove(&policy.heap, entry.index)
+		delete(policy.items, key)
+		policy.bytes -= entry.size
+	}
+}
+
+func (policy *lfuPolicy) Contains(key string) bool {
+	_, is_present := policy.items[key]
+	return is_present
+}
+
+func (policy *lfuPolicy) Len() int {
+	return len(policy.items)
+}
+
+func (policy *lfuPolicy) Bytes() int {
+	return policy.bytes
+}
+
+//Kljucevi po opadajucem (broj pristupa, vreme poslednjeg pristupa), obrnuto od redosleda izbacivanja
+func (policy *lfuPolicy) Keys() []string {
+	entries := make(lfuHeap, len(policy.heap))
+	copy(entries, policy.heap)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].freq != entries[j].freq {
+			return entries[i].freq > entries[j].freq
+		}
+		return entries[i].tick > entries[j].tick
+	})
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.key
+	}
+	return keys
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+//Izbacuje se stavka sa najmanje pristupa, a medju jednako cestim ona kojoj se najduze nije pristupalo
+func TestLFUPolicy(t *testing.T) {
+	policy := newLFUPolicy(300)
+	for _, key := range []string{"a", "b", "c"} {
+		policy.Put(key, cacheValue(key, 100))
+	}
+	policy.Get("a")
+	policy.Get("a")
+	policy.Get("c")
+	policy.Put("d", cacheValue("d", 100))
+	if policy.Contains("b") {
+		t.Fatalf("ocekivano da je izbacen b, kljucevi %v", policy.Keys())
+	}
+
+	//c i d imaju po dva pristupa, d je skoriji
+	policy.Get("d")
+	policy.Put("e", cacheValue("e", 100))
+	if policy.Contains("c") {
+		t.Fatalf("ocekivano da je izbacen c, kljucevi %v", policy.Keys())
+	}
+	if keys := policy.Keys(); !reflect.DeepEqual(keys, []string{"a", "d", "e"}) {
+		t.Fatalf("ocekivano [a d e], dobijeno %v", keys)
+	}
+}
+
+//Izmena vrednosti zadrzava broj pristupa
+func TestLFUPolicyUpdateKeepsFrequency(t *testing.T) {
+	policy := newLFUPolicy(200)
+	policy.Put("a", cacheValue("a", 100))
+	policy.Get("a")
+	policy.Put("b", cacheValue("b", 100))
+	policy.Put("a", cacheValue("a", 50))
+	policy.Put("c", cacheValue("c", 100))
+	if !policy.Contains("a") || policy.Contains("b") {
+		t.Fatalf("ocekivano da je izbacen b, kljucevi %v", policy.Keys())
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+)
+
+/*Politika izbacivanja jednog shard-a cache-a
+Politika ne zakljucava nista - poziva je shard dok drzi svoju bravu
+Put vraca broj stavki izbacenih da bi nova stala, stavka veca od kapaciteta se ne ubacuje
+Keys vraca kljuceve od najvrednijih ka onima koji bi prvi bili izbaceni*/
+type CachePolicy interface {
+	Get(key string) ([]byte, bool)
+	Put(key string, value []byte) int
+	Remove(key string)
+	Contains(key string) bool
+	Len() int
+	Bytes() int
+	Keys() []string
+}
+
+//Nazivi politika u konfiguraciji
+var cachePolicies = []string{"lru", "lfu", "2q", "arc", "tinylfu"}
+
+func checkCachePolicy(name string) bool {
+	for _, policy := range cachePolicies {
+		if policy == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*Pravi politiku zadatog naziva sa kapacitetom max_bytes*/
+func newCachePolicy(name string, max_bytes int) (CachePolicy, error) {
+	switch name {
+	case "lru":
+		return newLRUPolicy(max_bytes), nil
+	case "lfu":
+		return newLFUPolicy(max_bytes), nil
+	case "2q":
+		return newTwoQueuePolicy(max_bytes), nil
+	case "arc":
+		return newARCPolicy(max_bytes), nil
+	case "tinylfu":
+		return newTinyLFUPolicy(max_bytes), nil
+	}
+	return nil, fmt.Errorf("unknown cache policy %q", name)
+}
+
+/*Stavka cache-a
+Kod "duhova" (ghost - kljucevi nedavno izbacenih stavki) je vrednost nil, a size je velicina izbacene stavke
+segment je lista u kojoj se stavka trenutno nalazi, a freq, tick i index koristi samo LFU*/
+type cacheEntry struct {
+	key     string
+	value   []byte
+	size    int
+	segment *cacheSegment
+	freq    uint64
+	tick    uint64
+	index   int
+}
+
+func newCacheEntry(key string, value []byte) *cacheEntry {
+	return &cacheEntry{key: key, value: value, size: entrySize(key, value)}
+}
+
+func entrySize(key string, value []byte) int {
+	return len(key) + len(value)
+}
+
+/*Lista stavki sa zbirom velicina - na pocetku je najskorija stavka*/
+type cacheSegment struct {
+	list  *list.List
+	bytes int
+}
+
+func newCacheSegment() *cacheSegment {
+	return &cacheSegment{list: list.New()}
+}
+
+func (segment *cacheSegment) pushFront(entry *cacheEntry) *list.Element {
+	entry.segment = segment
+	segment.bytes += entry.size
+	return segment.list.PushFront(entry)
+}
+
+func (segment *cacheSegment) remove(element *list.Element) *cacheEntry {
+	entry := element.Value.(*cacheEntry)
+	segment.list.Remove(element)
+	segment.bytes -= entry.size
+	return entry
+}
+
+//Premesta stavku na pocetak druge (ili iste) liste
+func (segment *cacheSegment) moveTo(element *list.Element, to *cacheSegment) *list.Element {
+	if segment == to {
+		segment.list.MoveToFront(element)
+		return element
+	}
+	return to.pushFront(segment.remove(element))
+}
+
+//Menja vrednost stavke i azurira zbir velicina
+func (segment *cacheSegment) update(element *list.Element, value []byte) {
+	entry := element.Value.(*cacheEntry)
+	segment.bytes -= entry.size
+	entry.value = value
+	entry.size = entrySize(entry.key, value)
+	segment.bytes += entry.size
+}
+
+//Kljucevi iz vise listi redom, svaka od najskorije stavke
+func segmentKeys(segments ...*cacheSegment) []string {
+	keys := []string{}
+	for _, segment := range segments {
+		for element := segment.list.Front(); element != nil; element = element.Next() {
+			keys = append(keys, element.Value.(*cacheEntry).key)
+		}
+	}
+	return keys
+}
+
+/*LRU - izbacuje se stavka kojoj se najduze nije pristupalo*/
+type lruPolicy struct {
+	max_bytes int
+	items     map[string]*list.Element
+	lru       *cacheSegment
+}
+
+func newLRUPolicy(max_bytes int) *lruPolicy {
+	return &lruPolicy{max_bytes: max_bytes, items: make(map[string]*list.Element), lru: newCacheSegment()}
+}
+
+func (policy *lruPolicy) Get(key string) ([]byte, bool) {
+	element, is_present := policy.items[key]
+	if !is_present {
+		return nil, false
+	}
+	policy.lru.list.MoveToFront(element)
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (policy *lruPolicy) Put(key string, value []byte) int {
+	policy.Remove(key)
+	entry := newCacheEntry(key, value)
+	if entry.size > policy.max_bytes {
+		return 0
+	}
+	evicted := 0
+	for policy.lru.bytes+entry.size > policy.max_bytes {
+		delete(policy.items, policy.lru.remove(policy.lru.list.Back()).key)
+		evicted++
+	}
+	policy.items[key] = policy.lru.pushFront(entry)
+	return evicted
+}
+
+func (policy *lruPolicy) Remove(key string) {
+	if element, is_present := policy.items[key]; is_present {
+		policy.lru.remove(element)
+		delete(policy.items, key)
+	}
+}
+
+func (policy *lruPolicy) Contains(key string) bool {
+	_, is_present := policy.items[key]
+	return is_present
+}
+
+func (policy *lruPolicy) Len() int {
+	return len(policy.items)
+}
+
+func (policy *lruPolicy) Bytes() int {
+	return policy.lru.bytes
+}
+
+func (policy *lruPolicy) Keys() []string {
+	return segmentKeys(policy.lru)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+//Posle svake operacije politika ne prelazi kapacitet, Len i Keys se slazu sa Contains,
+//a broj stavki se menja tacno za ubacenu stavku umanjenu za broj izbacenih
+func TestCachePolicyInvariants(t *testing.T) {
+	for _, name := range cachePolicies {
+		t.Run(name, func(t *testing.T) {
+			const max_bytes = 2000
+			policy, err := newCachePolicy(name, max_bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			random := rand.New(rand.NewSource(1))
+			for op := 0; op < 20000; op++ {
+				key := "k" + strconv.Itoa(random.Intn(200))
+				before := policy.Len()
+				switch choice := random.Intn(10); {
+				case choice < 5:
+					policy.Get(key)
+					if policy.Len() != before {
+						t.Fatalf("operacija %d: Get promenio broj stavki", op)
+					}
+				case choice < 9:
+					present := policy.Contains(key)
+					evicted := policy.Put(key, make([]byte, random.Intn(150)))
+					expected := before - evicted
+					if !present {
+						expected++
+					}
+					if policy.Len() != expected {
+						t.Fatalf("operacija %d: Put(%s) izbacio %d, ocekivano %d stavki, dobijeno %d", op, key, evicted, expected, policy.Len())
+					}
+				default:
+					policy.Remove(key)
+					if policy.Contains(key) {
+						t.Fatalf("operacija %d: %s postoji posle Remove", op, key)
+					}
+				}
+				checkPolicyState(t, policy, max_bytes)
+			}
+		})
+	}
+}
+
+//Stavka veca od kapaciteta se ne ubacuje, a postojeca verzija kljuca se uklanja
+func TestCachePolicyRejectsOversized(t *testing.T) {
+	for _, name := range cachePolicies {
+		policy, _ := newCachePolicy(name, 100)
+		policy.Put("a", []byte("1"))
+		policy.Put("a", make([]byte, 200))
+		if value, found := policy.Get("a"); found {
+			t.Errorf("%s: ocekivano da a nije u cache-u, dobijeno %d bajtova", name, len(value))
+		}
+		if policy.Bytes() != 0 {
+			t.Errorf("%s: ocekivano 0 bajtova, dobijeno %d", name, policy.Bytes())
+		}
+	}
+}
+
+func checkPolicyState(t *testing.T, policy CachePolicy, max_bytes int) {
+	t.Helper()
+	if policy.Bytes() > max_bytes {
+		t.Fatalf("%d bajtova preko kapaciteta %d", policy.Bytes(), max_bytes)
+	}
+	keys := policy.Keys()
+	if len(keys) != policy.Len() {
+		t.Fatalf("Keys vraca %d kljuceva, Len %d", len(keys), policy.Len())
+	}
+	bytes := 0
+	for _, key := range keys {
+		if !policy.Contains(key) {
+			t.Fatalf("kljuc %s iz Keys nije u politici", key)
+		}
+		value, found := policy.Get(key)
+		if !found {
+			t.Fatalf("kljuc %s iz Keys se ne moze procitati", key)
+		}
+		bytes += entrySize(key, value)
+	}
+	if bytes != policy.Bytes() {
+		t.Fatalf("zbir velicina %d, Bytes %d", bytes, policy.Bytes())
+	}
+}
+
+//Zajednicki niz pristupa za benchmark: Zipf raspodela preko 10000 kljuceva,
+//a svaki deseti blok od 1000 pristupa je sekvencijalno skeniranje kljuceva koji se ne ponavljaju
+//Ako je postavljen KV_CACHE_TRACE, pristupi se citaju iz fajla (u redu kljuc i opciono velicina)
+type traceAccess struct {
+	key  string
+	size int
+}
+
+func cacheTrace(b *testing.B) []traceAccess {
+	if path := os.Getenv("KV_CACHE_TRACE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer file.Close()
+		trace := make([]traceAccess, 0)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			access := traceAccess{key: fields[0], size: 100}
+			if len(fields) > 1 {
+				if size, err := strconv.Atoi(fields[1]); err == nil && size >= 0 {
+					access.size = size
+				}
+			}
+			trace = append(trace, access)
+		}
+		if err := scanner.Err(); err != nil {
+			b.Fatal(err)
+		}
+		return trace
+	}
+
+	random := rand.New(rand.NewSource(42))
+	zipf := rand.NewZipf(random, 1.1, 1, 9999)
+	trace := make([]traceAccess, 0, 100000)
+	for block := 0; block < 100; block++ {
+		for i := 0; i < 1000; i++ {
+			if block%10 == 9 {
+				trace = append(trace, traceAccess{key: "scan" + strconv.Itoa(block*1000+i), size: 100})
+			} else {
+				trace = append(trace, traceAccess{key: "k" + strconv.FormatUint(zipf.Uint64(), 10), size: 100})
+			}
+		}
+	}
+	return trace
+}
+
+//Pusta zajednicki niz pristupa kroz svaku politiku, promasaj ubacuje vrednost kao get
+//Pored vremena prijavljuje procenat pogodaka (hit%) na poslednjem prolazu
+func BenchmarkCachePolicies(b *testing.B) {
+	trace := cacheTrace(b)
+	for _, name := range cachePolicies {
+		b.Run(name, func(b *testing.B) {
+			hits := uint64(0)
+			for n := 0; n < b.N; n++ {
+				cache, err := createCache(200*1000, 4, name)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, access := range trace {
+					if _, found := cache.Search(access.key); !found {
+						cache.Insert(access.key, make([]byte, access.size))
+					}
+				}
+				hits = cache.Statistics().Hits
+			}
+			b.ReportMetric(math.Round(float64(hits)/float64(len(trace))*10000)/100, "hit%")
+		})
+	}
+}
+
+//Vrednost takva da je stavka sa kljucem key velika tacno size bajtova
+func cacheValue(key string, size int) []byte {
+	return make([]byte, size-len(key))
+}
+
+func TestLRUPolicy(t *testing.T) {
+	policy := newLRUPolicy(300)
+	for _, key := range []string{"a", "b", "c"} {
+		policy.Put(key, cacheValue(key, 100))
+	}
+	policy.Get("a")
+	if evicted := policy.Put("d", cacheValue("d", 100)); evicted != 1 {
+		t.Fatalf("ocekivano 1 izbacivanje, dobijeno %d", evicted)
+	}
+	if policy.Contains("b") || !policy.Contains("a") || !policy.Contains("c") {
+		t.Fatalf("ocekivano da je izbacen b, kljucevi %v", policy.Keys())
+	}
+}
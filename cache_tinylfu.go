@@ -0,0 +1,207 @@
+package main
+
+import (
+	"container/list"
+	"github.com/spaolacci/murmur3"
+)
+
+/*W-TinyLFU (Einziger, Friedman i Manes) - nove stavke ulaze u mali LRU prozor (1% kapaciteta)
+Stavka izbacena iz prozora ulazi u glavni deo samo ako je cesca od stavke koju bi izbacila,
+a ucestalost se procenjuje skicom koja broji i pristupe kljucevima koji nisu u cache-u
+Glavni deo je segmentirani LRU: probation za nove stavke i protected (80%) za stavke pogodjene u probation*/
+type tinyLFUPolicy struct {
+	max_bytes     int
+	window_max    int
+	protected_max int
+	items         map[string]*list.Element
+	window        *cacheSegment
+	probation     *cacheSegment
+	protected     *cacheSegment
+	sketch        *frequencySketch
+}
+
+func newTinyLFUPolicy(max_bytes int) *tinyLFUPolicy {
+	window_max := max_bytes / 100
+	main_max := max_bytes - window_max
+	//Skica se dimenzionise za procenjen broj stavki, uz pretpostavku od 64 bajta po stavci
+	return &tinyLFUPolicy{
+		max_bytes:     max_bytes,
+		window_max:    window_max,
+		protected_max: main_max * 8 / 10,
+		items:         make(map[string]*list.Element),
+		window:        newCacheSegment(),
+		probation:     newCacheSegment(),
+		protected:     newCacheSegment(),
+		sketch:        newFrequencySketch(max_bytes / 64),
+	}
+}
+
+func (policy *tinyLFUPolicy) Get(key string) ([]byte, bool) {
+	policy.sketch.increment(key)
+	element, is_present := policy.items[key]
+	if !is_present {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	policy.access(element)
+	return entry.value, true
+}
+
+//Pogodak u probation unapredjuje stavku u protected, a visak iz protected se vraca u probation
+func (policy *tinyLFUPolicy) access(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	if entry.segment == policy.probation {
+		element = policy.probation.moveTo(element, policy.protected)
+		policy.items[entry.key] = element
+		for policy.protected.bytes > policy.protected_max && policy.protected.list.Len() > 1 {
+			demoted := policy.protected.list.Back()
+			policy.items[demoted.Value.(*cacheEntry).key] = policy.protected.moveTo(demoted, policy.probation)
+		}
+		return
+	}
+	entry.segment.list.MoveToFront(element)
+}
+
+func (policy *tinyLFUPolicy) Put(key string, value []byte) int {
+	if entrySize(key, value) > policy.max_bytes-policy.window_max {
+		policy.Remove(key)
+		return 0
+	}
+	if element, is_present := policy.items[key]; is_present {
+		element.Value.(*cacheEntry).segment.update(element, value)
+		policy.access(element)
+		return policy.shrinkMain()
+	}
+	policy.items[key] = policy.window.pushFront(newCacheEntry(key, value))
+	evicted := 0
+	for policy.window.bytes > policy.window_max {
+		candidate := policy.window.remove(policy.window.list.Back())
+		delete(policy.items, candidate.key)
+		evicted += policy.admit(candidate)
+	}
+	return evicted
+}
+
+/*Kandidat iz prozora ulazi u probation ako staje, ili ako je cesci od svih stavki koje bi izbacio
+Zrtve se biraju unapred, od kraja probation pa protected, i izbacuju se tek kada je kandidat primljen,
+pa odbijen kandidat ne izbacuje nista. Odbijen kandidat se broji kao jedna izbacena stavka*/
+func (policy *tinyLFUPolicy) admit(candidate *cacheEntry) int {
+	main_max := policy.max_bytes - policy.window_max
+	frequency := policy.sketch.estimate(candidate.key)
+	victims := make([]*list.Element, 0)
+	freed := 0
+	victim := policy.probation.list.Back()
+	in_protected := false
+	for policy.probation.bytes+policy.protected.bytes-freed+candidate.size > main_max {
+		if victim == nil && !in_protected {
+			victim = policy.protected.list.Back()
+			in_protected = true
+		}
+		entry := victim.Value.(*cacheEntry)
+		if frequency <= policy.sketch.estimate(entry.key) {
+			return 1
+		}
+		victims = append(victims, victim)
+		freed += entry.size
+		victim = victim.Prev()
+	}
+	for _, victim := range victims {
+		delete(policy.items, victim.Value.(*cacheEntry).segment.remove(victim).key)
+	}
+	policy.items[candidate.key] = policy.probation.pushFront(candidate)
+	return len(victims)
+}
+
+//Izbacuje stavke iz glavnog dela kada izmena vrednosti poveca njegovu velicinu
+func (policy *tinyLFUPolicy) shrinkMain() int {
+	main_max := policy.max_bytes - policy.window_max
+	evicted := 0
+	for policy.probation.bytes+policy.protected.bytes > main_max {
+		segment := policy.probation
+		if segment.list.Len() == 0 {
+			segment = policy.protected
+		}
+		delete(policy.items, segment.remove(segment.list.Back()).key)
+		evicted++
+	}
+	for policy.window.bytes > policy.window_max {
+		candidate := policy.window.remove(policy.window.list.Back())
+		delete(policy.items, candidate.key)
+		evicted += policy.admit(candidate)
+	}
+	return evicted
+}
+
+func (policy *tinyLFUPolicy) Remove(key string) {
+	if element, is_present := policy.items[key]; is_present {
+		element.Value.(*cacheEntry).segment.remove(element)
+		delete(policy.items, key)
+	}
+}
+
+func (policy *tinyLFUPolicy) Contains(key string) bool {
+	_, is_present := policy.items[key]
+	return is_present
+}
+
+func (policy *tinyLFUPolicy) Len() int {
+	return len(policy.items)
+}
+
+func (policy *tinyLFUPolicy) Bytes() int {
+	return policy.window.bytes + policy.probation.bytes + policy.protected.bytes
+}
+
+func (policy *tinyLFUPolicy) Keys() []string {
+	return segmentKeys(policy.protected, policy.probation, policy.window)
+}
+
+/*Count-Min skica sa 4-bitnim brojacima (do 15) za procenu ucestalosti kljuceva
+Posle sample_size brojanja svi brojaci se prepolove, pa stara ucestalost vremenom blede*/
+type frequencySketch struct {
+	width       uint32
+	counters    [4][]uint8
+	additions   int
+	sample_size int
+}
+
+func newFrequencySketch(items int) *frequencySketch {
+	width := uint32(64)
+	for int(width) < items && width < 1<<20 {
+		width <<= 1
+	}
+	sketch := &frequencySketch{width: width, sample_size: 10 * int(width)}
+	for i := range sketch.counters {
+		sketch.counters[i] = make([]uint8, width)
+	}
+	return sketch
+}
+
+func (sketch *frequencySketch) increment(key string) {
+	for i := range sketch.counters {
+		column := murmur3.Sum32WithSeed([]byte(key), uint32(i)) % sketch.width
+		if sketch.counters[i][column] < 15 {
+			sketch.counters[i][column]++
+		}
+	}
+	sketch.additions++
+	if sketch.additions >= sketch.sample_size {
+		for i := range sketch.counters {
+			for j := range sketch.counters[i] {
+				sketch.counters[i][j] /= 2
+			}
+		}
+		sketch.additions /= 2
+	}
+}
+
+func (sketch *frequencySketch) estimate(key string) uint8 {
+	estimate := uint8(15)
+	for i := range sketch.counters {
+		column := murmur3.Sum32WithSeed([]byte(key), uint32(i)) % sketch.width
+		if sketch.counters[i][column] < estimate {
+			estimate = sketch.counters[i][column]
+		}
+	}
+	return estimate
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+//Kandidat koji nije cesci od svih zrtava koje bi izbacio se odbija, a glavni deo ostaje netaknut
+//Kada postane cesci od svih, ulazi i izbacuje ih
+func TestTinyLFUPolicyRejectsWithoutEvicting(t *testing.T) {
+	policy := newTinyLFUPolicy(30000)
+	policy.Put("v1", cacheValue("v1", 10000))
+	policy.Put("v2", cacheValue("v2", 10000))
+	for i := 0; i < 5; i++ {
+		policy.Get("v2")
+	}
+	//Kandidatu treba mesta koje oslobadjaju tek obe zrtve, a cesci je samo od v1
+	policy.Get("c")
+	policy.Get("c")
+	if evicted := policy.Put("c", cacheValue("c", 20000)); evicted != 1 {
+		t.Fatalf("ocekivano 1 (odbijen kandidat), dobijeno %d", evicted)
+	}
+	if policy.Contains("c") || !policy.Contains("v1") || !policy.Contains("v2") {
+		t.Fatalf("ocekivano da su v1 i v2 sacuvani, kljucevi %v", policy.Keys())
+	}
+
+	for i := 0; i < 5; i++ {
+		policy.Get("c")
+	}
+	if evicted := policy.Put("c", cacheValue("c", 20000)); evicted != 2 {
+		t.Fatalf("ocekivano 2 izbacivanja, dobijeno %d", evicted)
+	}
+	if !policy.Contains("c") || policy.Contains("v1") || policy.Contains("v2") {
+		t.Fatalf("ocekivano da je primljen samo c, kljucevi %v", policy.Keys())
+	}
+}
+
+//Cesto citani kljucevi ostaju u cache-u dok prolazi skeniranje jednokratnih kljuceva
+func TestTinyLFUPolicyScanResistance(t *testing.T) {
+	policy := newTinyLFUPolicy(100000)
+	hot := make([]string, 20)
+	for i := range hot {
+		hot[i] = "h" + strconv.Itoa(i)
+		policy.Put(hot[i], cacheValue(hot[i], 100))
+		for j := 0; j < 10; j++ {
+			policy.Get(hot[i])
+		}
+	}
+	for i := 0; i < 3000; i++ {
+		key := "s" + strconv.Itoa(i)
+		if _, found := policy.Get(key); !found {
+			policy.Put(key, cacheValue(key, 100))
+		}
+	}
+	for _, key := range hot {
+		if !policy.Contains(key) {
+			t.Fatalf("skeniranje je izbacilo %s", key)
+		}
+	}
+}
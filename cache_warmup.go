@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*Fajl sa kljucevima iz cache-a, upisuje se pri urednom gasenju
+Za svaki kljuc: duzina (8 bajtova) pa kljuc, od najvrednijeg ka onom koji bi prvi bio izbacen*/
+const cacheKeysPath = "data/cachekeys.txt"
+
+/*Cuva kljuceve iz cache-a u fajl
+Upisuje se u privremeni fajl koji se zatim preimenuje, pa prekid upisa ne ostavlja polovican fajl*/
+func (cache *Cache) SaveKeys(path string) error {
+	//Direktorijum podataka ne postoji ako sistem jos nije nista upisao
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	length := make([]byte, 8)
+	for _, key := range cache.Keys() {
+		binary.LittleEndian.PutUint64(length, uint64(len(key)))
+		writer.Write(length)
+		writer.WriteString(key)
+	}
+	err = writer.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+/*Ucitava kljuceve sacuvane sa SaveKeys, nepostojeci fajl znaci da nema kljuceva*/
+func loadCacheKeys(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	length := make([]byte, 8)
+	keys := []string{}
+	for {
+		_, err = io.ReadFull(reader, length)
+		if err == io.EOF {
+			return keys, nil
+		}
+		if err != nil {
+			return keys, fmt.Errorf("%s: %w", path, err)
+		}
+		size := binary.LittleEndian.Uint64(length)
+		if size > 1<<30 {
+			return keys, fmt.Errorf("%s: neispravna duzina kljuca %d", path, size)
+		}
+		key := make([]byte, size)
+		if _, err = io.ReadFull(reader, key); err != nil {
+			return keys, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, string(key))
+	}
+}
+
+/*Rezultat zagrevanja cache-a*/
+type WarmupStats struct {
+	Keys     int //ucitanih kljuceva
+	Skipped  int //kljuceva koji vise ne postoje ili nisu ubaceni
+	Bytes    int
+	Duration time.Duration
+}
+
+func (stats WarmupStats) String() string {
+	return fmt.Sprintf("cache warmup: %d keys, %d skipped, %d bytes in %v", stats.Keys, stats.Skipped, stats.Bytes, stats.Duration)
+}
+
+//Koliko kljuceva se zagreva dok se drzi brava sistema, izmedju delova mogu da se izvrse druge operacije
+const warmupChunk = 64
+
+/*Zagreva cache kljucevima sacuvanim pri proslom gasenju, redom od najvrednijih kljuceva
+Vrednosti se citaju kao u get (memtabela, pa SSTabele uz negativni cache), ali se ne broje medju najcitanijim kljucevima
+Kljucevi se citaju u delovima od warmupChunk dok se drzi brava sistema, pa flush i kompakcija ne mogu da pocnu usred citanja
+Staje kada istekne max_time, kada se ucita max_bytes bajtova ili kada bi sledeca stavka izbacila neku iz cache-a*/
+func (sys *System) warmCache(keys []string, max_time time.Duration, max_bytes int) WarmupStats {
+	start := time.Now()
+	stats := WarmupStats{}
+	done := false
+	for len(keys) > 0 && !done {
+		chunk := keys
+		if len(chunk) > warmupChunk {
+			chunk = chunk[:warmupChunk]
+		}
+		keys = keys[len(chunk):]
+
+		sys.mutex.Lock()
+		for _, key := range chunk {
+			if time.Since(start) > max_time || stats.Bytes >= max_bytes {
+				done = true
+				break
+			}
+			if sys.cache.Contains(key) {
+				stats.Skipped++
+				continue
+			}
+			value := sys.memtable.GetElement(key)
+			if value == nil {
+				value = sys.readTables(key)
+			}
+			if value == nil {
+				stats.Skipped++
+				continue
+			}
+			if sys.cache.WouldEvict(key, value) {
+				done = true
+				break
+			}
+			sys.cache.Insert(key, value)
+			stats.Keys++
+			stats.Bytes += entrySize(key, value)
+		}
+		sys.mutex.Unlock()
+	}
+	stats.Duration = time.Since(start)
+	return stats
+}
+
+/*Ako je zagrevanje ukljuceno, pokrece ga u pozadini i vraca odmah
+Po zavrsetku ispisuje statistiku*/
+func (sys *System) startCacheWarmup() {
+	if !sys.config.cache_warmup {
+		return
+	}
+	keys, err := loadCacheKeys(cacheKeysPath)
+	if err != nil {
+		fmt.Println(err)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	max_time, max_bytes := time.Duration(sys.config.cache_warmup_seconds)*time.Second, sys.config.cache_warmup_bytes
+	go func() {
+		stats := sys.warmCache(keys, max_time, max_bytes)
+		fmt.Println(stats.String())
+	}()
+}
@@ -0,0 +1,128 @@
+package cms
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/spaolacci/murmur3"
+	"kv/bloom"
+	"math"
+)
+
+//Count-Min Sketch: depth redova po width brojaca, svaki red ima svoju hash funkciju
+//Procena nikad nije manja od stvarnog broja, a sa verovatnocom 1-delta je veca za najvise epsilon*ukupno
+type CountMinSketch struct {
+	width    uint32
+	depth    uint32
+	seeds    []uint32 //seme murmur3 funkcije svakog reda, kao u bloom.CreateHashFunctions
+	counters []uint64 //depth*width, red po red
+}
+
+//Zaglavlje na disku: width 4B, depth 4B, pa seme po 4B i brojaci po 8B
+const headerSize = 8
+
+//Najvise brojaca u skici (32MB), manji epsilon ili delta se odbijaju umesto da se dimenzije prekorace
+const MaxCounters = 1 << 22
+
+var (
+	ErrParameters   = errors.New("epsilon and delta must be between 0 and 1")
+	ErrTooLarge     = errors.New("count-min sketch would exceed the maximum number of counters, increase epsilon or delta")
+	ErrCorrupted    = errors.New("count-min sketch corrupted")
+	ErrIncompatible = errors.New("count-min sketches have different dimensions or seeds")
+)
+
+//Sirina se racuna kao e/epsilon, a dubina kao ln(1/delta)
+func New(epsilon float64, delta float64) (*CountMinSketch, error) {
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return nil, ErrParameters
+	}
+	//Dimenzije se proveravaju kao realni brojevi, pre pretvaranja u uint32 koje bi prekoracilo za mali epsilon
+	width, depth := math.Ceil(math.E/epsilon), math.Ceil(math.Log(1/delta))
+	if width*depth > MaxCounters {
+		return nil, ErrTooLarge
+	}
+	_, seeds := bloom.CreateHashFunctions(uint(depth))
+	return &CountMinSketch{width: uint32(width), depth: uint32(depth), seeds: seeds, counters: make([]uint64, int(width*depth))}, nil
+}
+
+func (sketch *CountMinSketch) column(row uint32, item string) uint32 {
+	return murmur3.Sum32WithSeed([]byte(item), sketch.seeds[row]) % sketch.width
+}
+
+func (sketch *CountMinSketch) Add(item string, count uint64) {
+	for row := uint32(0); row < sketch.depth; row++ {
+		sketch.counters[row*sketch.width+sketch.column(row, item)] += count
+	}
+}
+
+//Procena broja pojavljivanja - najmanji brojac medju redovima
+func (sketch *CountMinSketch) Estimate(item string) uint64 {
+	estimate := uint64(math.MaxUint64)
+	for row := uint32(0); row < sketch.depth; row++ {
+		if counter := sketch.counters[row*sketch.width+sketch.column(row, item)]; counter < estimate {
+			estimate = counter
+		}
+	}
+	return estimate
+}
+
+//Sabira drugu skicu u ovu, skice moraju imati iste dimenzije i seme
+func (sketch *CountMinSketch) Merge(other *CountMinSketch) error {
+	if sketch.width != other.width || sketch.depth != other.depth {
+		return ErrIncompatible
+	}
+	for i := range sketch.seeds {
+		if sketch.seeds[i] != other.seeds[i] {
+			return ErrIncompatible
+		}
+	}
+	for i := range sketch.counters {
+		sketch.counters[i] += other.counters[i]
+	}
+	return nil
+}
+
+//Pravi praznu skicu sa istim dimenzijama i semenom, kako bi se kasnije mogla spojiti sa ovom
+func (sketch *CountMinSketch) Compatible() *CountMinSketch {
+	seeds := make([]uint32, len(sketch.seeds))
+	copy(seeds, sketch.seeds)
+	return &CountMinSketch{width: sketch.width, depth: sketch.depth, seeds: seeds, counters: make([]uint64, len(sketch.counters))}
+}
+
+func (sketch *CountMinSketch) Marshal() []byte {
+	bytes := make([]byte, headerSize+4*len(sketch.seeds)+8*len(sketch.counters))
+	binary.LittleEndian.PutUint32(bytes[0:4], sketch.width)
+	binary.LittleEndian.PutUint32(bytes[4:8], sketch.depth)
+	offset := headerSize
+	for _, seed := range sketch.seeds {
+		binary.LittleEndian.PutUint32(bytes[offset:], seed)
+		offset += 4
+	}
+	for _, counter := range sketch.counters {
+		binary.LittleEndian.PutUint64(bytes[offset:], counter)
+		offset += 8
+	}
+	return bytes
+}
+
+func Unmarshal(bytes []byte) (*CountMinSketch, error) {
+	if len(bytes) < headerSize {
+		return nil, ErrCorrupted
+	}
+	width := binary.LittleEndian.Uint32(bytes[0:4])
+	depth := binary.LittleEndian.Uint32(bytes[4:8])
+	if width == 0 || depth == 0 || uint64(width)*uint64(depth) > MaxCounters ||
+		uint64(len(bytes)) != headerSize+4*uint64(depth)+8*uint64(width)*uint64(depth) {
+		return nil, ErrCorrupted
+	}
+	sketch := &CountMinSketch{width: width, depth: depth, seeds: make([]uint32, depth), counters: make([]uint64, width*depth)}
+	offset := headerSize
+	for i := range sketch.seeds {
+		sketch.seeds[i] = binary.LittleEndian.Uint32(bytes[offset:])
+		offset += 4
+	}
+	for i := range sketch.counters {
+		sketch.counters[i] = binary.LittleEndian.Uint64(bytes[offset:])
+		offset += 8
+	}
+	return sketch, nil
+}
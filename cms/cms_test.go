@@ -0,0 +1,116 @@
+package cms
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+//Procena nikad nije manja od stvarnog broja, a za skoro sve elemente je veca za najvise epsilon*ukupno
+func TestNeverUnderestimates(t *testing.T) {
+	const epsilon, delta = 0.001, 0.01
+	sketch, err := New(epsilon, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(random, 1.2, 1, 9999)
+	counts := make(map[string]uint64)
+	total := uint64(0)
+	for i := 0; i < 200000; i++ {
+		item := "k" + strconv.FormatUint(zipf.Uint64(), 10)
+		count := uint64(1 + random.Intn(3))
+		sketch.Add(item, count)
+		counts[item] += count
+		total += count
+	}
+	over := 0
+	for item, count := range counts {
+		estimate := sketch.Estimate(item)
+		if estimate < count {
+			t.Fatalf("%s: procena %d manja od stvarnog broja %d", item, estimate, count)
+		}
+		if float64(estimate-count) > epsilon*float64(total) {
+			over++
+		}
+	}
+	if float64(over) > 2*delta*float64(len(counts)) {
+		t.Fatalf("%d od %d procena preko granice epsilon*ukupno", over, len(counts))
+	}
+	if estimate := sketch.Estimate("nije dodat"); float64(estimate) > epsilon*float64(total) {
+		t.Fatalf("procena elementa koji nije dodat %d", estimate)
+	}
+}
+
+//Spajaju se samo skice istih dimenzija i semena, a spajanje sabira brojeve
+func TestMerge(t *testing.T) {
+	first, _ := New(0.01, 0.01)
+	wider, _ := New(0.001, 0.01)
+	deeper, _ := New(0.01, 0.0001)
+	for _, other := range []*CountMinSketch{wider, deeper} {
+		if err := first.Merge(other); err != ErrIncompatible {
+			t.Fatalf("ocekivano %v, dobijeno %v", ErrIncompatible, err)
+		}
+	}
+	reseeded := first.Compatible()
+	reseeded.seeds[0]++
+	if err := first.Merge(reseeded); err != ErrIncompatible {
+		t.Fatalf("ocekivano %v za drugo seme, dobijeno %v", ErrIncompatible, err)
+	}
+
+	second := first.Compatible()
+	first.Add("a", 3)
+	second.Add("a", 4)
+	second.Add("b", 1)
+	if err := first.Merge(second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Estimate("a") < 7 || first.Estimate("b") < 1 {
+		t.Fatalf("posle spajanja a=%d, b=%d", first.Estimate("a"), first.Estimate("b"))
+	}
+}
+
+//Parametri koji bi prekoracili MaxCounters se odbijaju, i pri pravljenju i pri ucitavanju
+func TestMaxCounters(t *testing.T) {
+	for _, parameters := range [][2]float64{{1e-7, 0.01}, {1e-4, 1e-300}, {math.SmallestNonzeroFloat64, 0.5}} {
+		if _, err := New(parameters[0], parameters[1]); err != ErrTooLarge {
+			t.Errorf("epsilon %g, delta %g: ocekivano %v, dobijeno %v", parameters[0], parameters[1], ErrTooLarge, err)
+		}
+	}
+	for _, parameters := range [][2]float64{{0, 0.5}, {0.5, 1}, {-1, 0.5}} {
+		if _, err := New(parameters[0], parameters[1]); err != ErrParameters {
+			t.Errorf("epsilon %g, delta %g: ocekivano %v, dobijeno %v", parameters[0], parameters[1], ErrParameters, err)
+		}
+	}
+	if _, err := New(math.E/MaxCounters, 0.5); err != nil {
+		t.Fatalf("skica od tacno MaxCounters brojaca: %v", err)
+	}
+
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header[0:4], MaxCounters)
+	binary.LittleEndian.PutUint32(header[4:8], 2)
+	if _, err := Unmarshal(header); err != ErrCorrupted {
+		t.Fatalf("ocekivano %v za zaglavlje preko MaxCounters, dobijeno %v", ErrCorrupted, err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	sketch, _ := New(0.01, 0.01)
+	for i := 0; i < 1000; i++ {
+		sketch.Add("k"+strconv.Itoa(i%100), uint64(i))
+	}
+	bytes := sketch.Marshal()
+	loaded, err := Unmarshal(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, sketch) {
+		t.Fatal("skica se menja serijalizacijom")
+	}
+	if _, err := Unmarshal(bytes[:len(bytes)-1]); err != ErrCorrupted {
+		t.Fatalf("ocekivano %v za odsecen zapis, dobijeno %v", ErrCorrupted, err)
+	}
+}
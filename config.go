@@ -0,0 +1,455 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"kv/bloom"
+	"kv/filter"
+	"kv/merkle_tree"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Objekat koji ima sva podesavanja za projekat
+type ConfigObj struct {
+
+	//WAL
+	batch_size   int
+	segment_size int
+	low_w_mark   int
+
+	//Token Bucket
+	tokens  int
+	minutes int
+
+	//LRU Cache
+	cache_limit  int    //max velicina u bajtovima (kljucevi + vrednosti)
+	cache_shards int    //broj shard-ova, svaki ima svoju bravu
+	cache_policy string //politika izbacivanja: lru, lfu, 2q, arc ili tinylfu
+
+	//Zagrevanje cache-a pri pokretanju kljucevima sacuvanim pri gasenju
+	cache_warmup         bool
+	cache_warmup_seconds int //max trajanje zagrevanja
+	cache_warmup_bytes   int //max bajtova ucitanih pri zagrevanju
+
+	//Negativni cache
+	negative_cache_size int //max broj zapamcenih promasaja, 0 iskljucuje negativni cache
+	negative_cache_ttl  int //koliko dugo se promasaj pamti, u sekundama
+
+	//Pracenje vrucih kljuceva
+	hot_keys_capacity  int  //broj kljuceva koji se prate u svakom delu prozora
+	hot_keys_window    int  //duzina kliznog prozora u sekundama
+	hot_keys_admission bool //kada je cache pun, u njega ulaze samo vruci kljucevi
+
+	//Memtable
+	mem_max_size int
+	threshold    float64 //procenentualno broj nakon kojeg se Flush-uje
+
+	//Filter SSTabela
+	bloom_precision float64     //zeljena stopa laznih pozitiva
+	filter_type     filter.Type //bloom ili cuckoo
+
+	//Merkle stablo
+	merkle_hash merkle_tree.Algorithm //hash funkcija za nove SSTabele
+
+	//Kes otvorenih SSTabela
+	table_cache_size  int //max broj otvorenih tabela
+	table_cache_bytes int //max zbir velicina ucitanih filtera i summary-ja
+	block_cache_bytes int //max velicina blok kesa data fajlova, 0 iskljucuje kes
+
+	//Prefiksni bloom filteri
+	prefix_extractor *bloom.PrefixExtractor //nil - bez prefiksnih filtera
+
+	//LSM stabla i kompakcije
+	max_height      int //max visina lsm stabla (BEZ Memtabele)
+	compaction_size int //broj tabela koje se spajaju
+
+	//Odakle je procitano svako podesavanje: "default", putanja fajla ili "env NAZIV"
+	sources map[string]string
+}
+
+//Kreira objekat sa podrazumevanim vrednostima
+func Default() *ConfigObj {
+	return &ConfigObj{
+		batch_size:   3,
+		segment_size: 6,
+		low_w_mark:   3,
+
+		tokens:  50,
+		minutes: 1,
+
+		cache_limit:  1 << 20,
+		cache_shards: 16,
+		cache_policy: "lru",
+
+		cache_warmup:         false,
+		cache_warmup_seconds: 5,
+		cache_warmup_bytes:   1 << 20,
+
+		negative_cache_size: 0,
+		negative_cache_ttl:  30,
+
+		hot_keys_capacity:  100,
+		hot_keys_window:    60,
+		hot_keys_admission: false,
+
+		mem_max_size: 5,
+		threshold:    80,
+
+		bloom_precision: 0.1,
+		filter_type:     filter.Bloom,
+
+		merkle_hash: merkle_tree.SHA256,
+
+		table_cache_size:  16,
+		table_cache_bytes: 8 << 20,
+		block_cache_bytes: 4 << 20,
+
+		max_height:      3,
+		compaction_size: 2,
+
+		sources: make(map[string]string),
+	}
+}
+
+/*Jedno podesavanje - naziv je "sekcija.kljuc" u YAML/JSON fajlu
+set proverava i upisuje vrednost, a value vraca trenutnu vrednost kao tekst
+restart - promena vazi tek posle ponovnog pokretanja, ponovno ucitavanje je ne primenjuje*/
+type configSetting struct {
+	name    string
+	set     func(config *ConfigObj, value string) error
+	value   func(config *ConfigObj) string
+	restart bool
+}
+
+func (setting configSetting) restartOnly() configSetting {
+	setting.restart = true
+	return setting
+}
+
+//Sva podesavanja, redom kojim ih ispisuje PrintConfig
+var configSettings = []configSetting{
+	intSetting("wal.batchSize", 1, 15, func(c *ConfigObj) *int { return &c.batch_size }),
+	intSetting("wal.segmentSize", 2, 10, func(c *ConfigObj) *int { return &c.segment_size }),
+	intSetting("wal.lowWaterMark", 1, 10, func(c *ConfigObj) *int { return &c.low_w_mark }),
+
+	intSetting("tokenBucket.tokens", 1, 10000, func(c *ConfigObj) *int { return &c.tokens }),
+	intSetting("tokenBucket.minutes", 1, 10, func(c *ConfigObj) *int { return &c.minutes }),
+
+	intSetting("memtable.maxSize", 1, 100000, func(c *ConfigObj) *int { return &c.mem_max_size }),
+	floatSetting("memtable.threshold", 0.1, 100, func(c *ConfigObj) *float64 { return &c.threshold }),
+
+	intSetting("cache.limit", 0, 1<<30, func(c *ConfigObj) *int { return &c.cache_limit }),
+	intSetting("cache.shards", 1, 256, func(c *ConfigObj) *int { return &c.cache_shards }),
+	{
+		name: "cache.policy",
+		set: func(c *ConfigObj, value string) error {
+			if !checkCachePolicy(value) {
+				return fmt.Errorf("mora biti jedno od: %s", strings.Join(cachePolicies, ", "))
+			}
+			c.cache_policy = value
+			return nil
+		},
+		value: func(c *ConfigObj) string { return c.cache_policy },
+	},
+	boolSetting("cache.warmup", func(c *ConfigObj) *bool { return &c.cache_warmup }).restartOnly(),
+	intSetting("cache.warmupSeconds", 1, 3600, func(c *ConfigObj) *int { return &c.cache_warmup_seconds }).restartOnly(),
+	intSetting("cache.warmupBytes", 1, 1<<30, func(c *ConfigObj) *int { return &c.cache_warmup_bytes }).restartOnly(),
+
+	intSetting("negativeCache.size", 0, 10000000, func(c *ConfigObj) *int { return &c.negative_cache_size }),
+	intSetting("negativeCache.ttl", 1, 86400, func(c *ConfigObj) *int { return &c.negative_cache_ttl }),
+
+	intSetting("hotKeys.capacity", 1, 100000, func(c *ConfigObj) *int { return &c.hot_keys_capacity }).restartOnly(),
+	intSetting("hotKeys.window", 1, 86400, func(c *ConfigObj) *int { return &c.hot_keys_window }).restartOnly(),
+	boolSetting("hotKeys.admission", func(c *ConfigObj) *bool { return &c.hot_keys_admission }),
+
+	floatSetting("filter.precision", 0.000001, 0.9, func(c *ConfigObj) *float64 { return &c.bloom_precision }),
+	{
+		name: "filter.type",
+		set: func(c *ConfigObj, value string) error {
+			t, err := filter.ParseType(value)
+			if err != nil {
+				return errors.New("mora biti bloom ili cuckoo")
+			}
+			c.filter_type = t
+			return nil
+		},
+		value: func(c *ConfigObj) string { return c.filter_type.String() },
+	},
+	{
+		name: "filter.prefixExtractor",
+		set: func(c *ConfigObj, value string) error {
+			extractor, err := bloom.ParsePrefixExtractor(value)
+			if err != nil {
+				return err
+			}
+			c.prefix_extractor = extractor
+			return nil
+		},
+		value: func(c *ConfigObj) string { return c.prefix_extractor.String() },
+	},
+
+	{
+		name: "merkle.hash",
+		set: func(c *ConfigObj, value string) error {
+			algorithm, err := merkle_tree.ParseAlgorithm(value)
+			if err != nil || algorithm == merkle_tree.SHA1 { //SHA-1 se samo cita iz starih tabela
+				return errors.New("mora biti sha256 ili sha512")
+			}
+			c.merkle_hash = algorithm
+			return nil
+		},
+		value: func(c *ConfigObj) string { return c.merkle_hash.String() },
+	},
+
+	intSetting("tableCache.size", 1, 10000, func(c *ConfigObj) *int { return &c.table_cache_size }),
+	intSetting("tableCache.bytes", 1024, 1<<30, func(c *ConfigObj) *int { return &c.table_cache_bytes }),
+	intSetting("blockCache.bytes", 0, 1<<30, func(c *ConfigObj) *int { return &c.block_cache_bytes }),
+
+	intSetting("lsm.maxHeight", 1, 10, func(c *ConfigObj) *int { return &c.max_height }).restartOnly(),
+	intSetting("lsm.compactionSize", 2, 10, func(c *ConfigObj) *int { return &c.compaction_size }),
+}
+
+func intSetting(name string, min int, max int, field func(c *ConfigObj) *int) configSetting {
+	return configSetting{
+		name: name,
+		set: func(c *ConfigObj, value string) error {
+			correct, val := CheckValInt(value, min, max)
+			if !correct {
+				return fmt.Errorf("mora biti ceo broj od %d do %d", min, max)
+			}
+			*field(c) = val
+			return nil
+		},
+		value: func(c *ConfigObj) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func floatSetting(name string, min float64, max float64, field func(c *ConfigObj) *float64) configSetting {
+	return configSetting{
+		name: name,
+		set: func(c *ConfigObj, value string) error {
+			correct, val := CheckValFloat(value, min, max)
+			if !correct {
+				return fmt.Errorf("mora biti broj od %g do %g", min, max)
+			}
+			*field(c) = val
+			return nil
+		},
+		value: func(c *ConfigObj) string { return strconv.FormatFloat(*field(c), 'g', -1, 64) },
+	}
+}
+
+func boolSetting(name string, field func(c *ConfigObj) *bool) configSetting {
+	return configSetting{
+		name: name,
+		set: func(c *ConfigObj, value string) error {
+			val, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.New("mora biti true ili false")
+			}
+			*field(c) = val
+			return nil
+		},
+		value: func(c *ConfigObj) string { return strconv.FormatBool(*field(c)) },
+	}
+}
+
+//Naziv promenljive okruzenja koja menja podesavanje: cache.warmupBytes -> KV_CACHE_WARMUP_BYTES
+func envName(name string) string {
+	var builder strings.Builder
+	builder.WriteString("KV_")
+	for _, c := range name {
+		switch {
+		case c == '.':
+			builder.WriteByte('_')
+		case c >= 'A' && c <= 'Z':
+			builder.WriteByte('_')
+			builder.WriteRune(c)
+		default:
+			builder.WriteString(strings.ToUpper(string(c)))
+		}
+	}
+	return builder.String()
+}
+
+/*Sve greske konfiguracije, svaka u svom redu*/
+type ConfigErrors []error
+
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+//Fajlovi koji se traze redom ako putanja nije zadata promenljivom KV_CONFIG
+var configFiles = []string{"config.yaml", "config.yml", "config.json"}
+
+//Putanja konfiguracionog fajla, "" ako ne postoji nijedan
+func configPath() string {
+	if path, found := os.LookupEnv("KV_CONFIG"); found {
+		return path
+	}
+	for _, path := range configFiles {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+/*Ucitava konfiguraciju: podrazumevane vrednosti, zatim fajl (YAML ili JSON po ekstenziji),
+zatim promenljive okruzenja KV_SEKCIJA_KLJUC. Prazna putanja znaci da se fajl ne cita
+Neispravne vrednosti ne menjaju podesavanje, a sve greske se vracaju zajedno kao ConfigErrors*/
+func LoadConfig(path string) (*ConfigObj, error) {
+	config := Default()
+	for _, setting := range configSettings {
+		config.sources[setting.name] = "default"
+	}
+	errs := ConfigErrors{}
+
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return config, ConfigErrors{fmt.Errorf("%s: %w", path, err)}
+		}
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			setting, found := findSetting(name)
+			if !found {
+				errs = append(errs, fmt.Errorf("%s: nepoznato podesavanje %s", path, name))
+				continue
+			}
+			if err := setting.set(config, values[name]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s=%q: %s", path, name, values[name], err))
+				continue
+			}
+			config.sources[name] = path
+		}
+	}
+
+	for _, setting := range configSettings {
+		name := envName(setting.name)
+		value, found := os.LookupEnv(name)
+		if !found {
+			continue
+		}
+		if err := setting.set(config, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q: %s", name, value, err))
+			continue
+		}
+		config.sources[setting.name] = "env " + name
+	}
+	errs = append(errs, config.validate()...)
+
+	if len(errs) != 0 {
+		return config, errs
+	}
+	return config, nil
+}
+
+//Provere koje zavise od vise podesavanja, rade se kada su procitani i fajl i promenljive okruzenja
+func (config *ConfigObj) validate() ConfigErrors {
+	errs := ConfigErrors{}
+	if config.low_w_mark > config.segment_size {
+		errs = append(errs, fmt.Errorf("wal.lowWaterMark=%d (%s) ne sme biti veci od wal.segmentSize=%d (%s)",
+			config.low_w_mark, config.sources["wal.lowWaterMark"], config.segment_size, config.sources["wal.segmentSize"]))
+	}
+	return errs
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var values map[string]string
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		values, err = parseJSON(file)
+	} else {
+		values, err = parseYAML(file)
+	}
+	//Prazan fajl je najcesce fajl koji se upravo prepisuje, a ne namera da sve bude podrazumevano
+	if err == nil && len(values) == 0 {
+		err = errors.New("fajl ne sadrzi nijedno podesavanje")
+	}
+	return values, err
+}
+
+func findSetting(name string) (configSetting, bool) {
+	for _, setting := range configSettings {
+		if setting.name == name {
+			return setting, true
+		}
+	}
+	return configSetting{}, false
+}
+
+//Ucitava konfiguraciju za pokretanje programa i komandi
+//Ako neko podesavanje nije ispravno, ispisuju se sve greske i program se zavrsava
+func readConfig() *ConfigObj {
+	config, err := LoadConfig(configPath())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	return config
+}
+
+//Funkcija proverava ispravnost vrednosti u eksternoj konfiguraciji za CELE BROJEVE
+//min i max su opsezi u kojima se vrednost moze naci
+//Vraca indikator - true = ispravno, false = neispravno i konvertovanu vrednost ukoliko je tacno, -1 ukoliko je netacno
+func CheckValInt(val string, min int, max int) (bool, int) {
+	value, err := strconv.Atoi(val)
+	if err != nil { //podatak nije celobrojnog tipa
+		return false, -1
+	} else {
+		if value < min || value > max { //van opsega
+			return false, -1
+		} else {
+			return true, value //sve je uredu
+		}
+	}
+
+}
+
+//Funkcija proverava ispravnost vrednosti u eksternoj konfiguraciji za REALNE BROJEVE
+//min i max su opsezi u kojima se vrednost moze naci
+//Vraca indikator - true = ispravno, false = neispravno i konvertovanu vrednost ukoliko je tacno, -1 ukoliko je netacno
+func CheckValFloat(val string, min float64, max float64) (bool, float64) {
+	value, err := strconv.ParseFloat(val, 64)
+	if err != nil { //podatak nije realnog tipa
+		return false, -1
+	} else {
+		if value < min || value > max { //van opsega
+			return false, -1
+		} else {
+			return true, value //sve je uredu
+		}
+	}
+
+}
+
+//Ispisuje vazece vrednosti svih podesavanja i odakle je koja procitana
+func (config *ConfigObj) PrintConfig() {
+	for _, setting := range configSettings {
+		source := config.sources[setting.name]
+		if source == "" {
+			source = "default"
+		}
+		if setting.restart {
+			source += ", restart"
+		}
+		fmt.Printf("%-26s %-12s (%s)\n", setting.name, setting.value(config), source)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"kv/SSTable"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/*Rezultat ponovnog ucitavanja konfiguracije, za svako promenjeno podesavanje "naziv: stara -> nova"*/
+type ReloadResult struct {
+	Applied []string //promene koje su primenjene odmah
+	Restart []string //promene koje vaze tek posle ponovnog pokretanja, do tada ostaje stara vrednost
+}
+
+func (result ReloadResult) String() string {
+	if len(result.Applied) == 0 && len(result.Restart) == 0 {
+		return "konfiguracija: nema promena"
+	}
+	text := ""
+	for _, change := range result.Applied {
+		text += "konfiguracija: primenjeno " + change + "\n"
+	}
+	for _, change := range result.Restart {
+		text += "konfiguracija: vazi posle ponovnog pokretanja " + change + "\n"
+	}
+	return text[:len(text)-1]
+}
+
+/*Ponovo cita konfiguraciju sa putanje i primenjuje promene koje se mogu menjati u radu
+Neispravan fajl ne menja nista - vracaju se sve greske. Inace se nova konfiguracija primenjuje odjednom,
+dok sistem drzi bravu, pa nijedna operacija ne vidi deo starih i deo novih podesavanja*/
+func (sys *System) ReloadConfig(path string) (ReloadResult, error) {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	old := sys.config
+	result := ReloadResult{}
+	for _, setting := range configSettings {
+		before, after := setting.value(old), setting.value(config)
+		if before == after {
+			continue
+		}
+		change := setting.name + ": " + before + " -> " + after
+		if setting.restart {
+			result.Restart = append(result.Restart, change)
+			setting.set(config, before)
+			config.sources[setting.name] = old.sources[setting.name]
+			continue
+		}
+		result.Applied = append(result.Applied, change)
+	}
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+	sys.config = config
+	sys.applyConfig(old)
+	return result, nil
+}
+
+//Prosledjuje novu konfiguraciju delovima sistema, old je prethodna konfiguracija
+func (sys *System) applyConfig(old *ConfigObj) {
+	config := sys.config
+	sys.initializeConfigs(old.prefix_extractor.String() != config.prefix_extractor.String())
+	sys.memtable.max_size = config.mem_max_size
+	sys.memtable.threshold = config.threshold
+
+	if old.cache_limit != config.cache_limit || old.cache_shards != config.cache_shards || old.cache_policy != config.cache_policy {
+		cache, err := sys.cache.rebuild(config.cache_limit, config.cache_shards, config.cache_policy)
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			sys.cache = cache
+		}
+	}
+	if old.negative_cache_size != config.negative_cache_size || old.negative_cache_ttl != config.negative_cache_ttl {
+		sys.negative = createNegativeCache(config.negative_cache_size, time.Duration(config.negative_cache_ttl)*time.Second)
+	}
+}
+
+//Ponovo ucitava konfiguraciju i ispisuje sta je promenjeno
+func (sys *System) reloadConfig() {
+	path := configPath()
+	result, err := sys.ReloadConfig(path)
+	if err != nil {
+		fmt.Println("konfiguracija nije promenjena:")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(result)
+}
+
+//Na svaki SIGHUP ponovo ucitava konfiguraciju
+func (sys *System) watchConfig() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			sys.reloadConfig()
+		}
+	}()
+}
+
+//Prosledjuje konfiguraciju paketima koji imaju svoja podesavanja
+//Promena ekstraktora prefiksa zatvara otvorene tabele, pa se on postavlja samo pri pokretanju i kada se promeni
+func (sys *System) initializeConfigs(prefix bool) {
+	InitializeTokenBucketConfigs(uint32(sys.config.tokens), uint32(sys.config.minutes))
+	InitializeWALConfigs(sys.config.batch_size, sys.config.segment_size, sys.config.low_w_mark)
+	SSTable.InitializeMerkleConfigs(sys.config.merkle_hash)
+	SSTable.InitializeFilterConfigs(sys.config.filter_type)
+	SSTable.InitializeTableCacheConfigs(sys.config.table_cache_size, int64(sys.config.table_cache_bytes))
+	SSTable.InitializeBlockCacheConfigs(int64(sys.config.block_cache_bytes))
+	if prefix {
+		SSTable.InitializePrefixConfigs(sys.config.prefix_extractor)
+	}
+}
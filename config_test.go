@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//Interval token bucket-a je ceo broj minuta - razlomak se odbija umesto da se tiho odsece
+func TestTokenBucketMinutesIsInteger(t *testing.T) {
+	os.Setenv("KV_TOKEN_BUCKET_MINUTES", "1.5")
+	defer os.Unsetenv("KV_TOKEN_BUCKET_MINUTES")
+	config, err := LoadConfig("")
+	if err == nil {
+		t.Fatal("ocekivana greska za 1.5 minuta")
+	}
+	if config.minutes != Default().minutes {
+		t.Fatalf("ocekivana podrazumevana vrednost %d, dobijeno %d", Default().minutes, config.minutes)
+	}
+
+	os.Setenv("KV_TOKEN_BUCKET_MINUTES", "3")
+	config, err = LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.minutes != 3 {
+		t.Fatalf("ocekivano 3, dobijeno %d", config.minutes)
+	}
+}
+
+//Isporucen config.yaml je ispravan
+func TestShippedConfig(t *testing.T) {
+	if _, err := LoadConfig("config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//Vrednosti iz fajla, promenljive okruzenja preko njih i provera wal.lowWaterMark <= wal.segmentSize
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("wal:\n  segmentSize: 4\n  lowWaterMark: 5\ncache:\n  policy: arc\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "wal.lowWaterMark") {
+		t.Fatalf("ocekivana greska za lowWaterMark veci od segmentSize, dobijeno %v", err)
+	}
+
+	os.Setenv("KV_WAL_SEGMENT_SIZE", "5")
+	defer os.Unsetenv("KV_WAL_SEGMENT_SIZE")
+	config, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.segment_size != 5 || config.low_w_mark != 5 || config.cache_policy != "arc" {
+		t.Fatalf("ocekivano 5, 5, arc, dobijeno %d, %d, %s", config.segment_size, config.low_w_mark, config.cache_policy)
+	}
+	if config.sources["wal.segmentSize"] != "env KV_WAL_SEGMENT_SIZE" || config.sources["wal.lowWaterMark"] != path {
+		t.Fatalf("pogresni izvori %v", config.sources)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("wal:\n  nepoznato: 1\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("ocekivana greska za nepoznato podesavanje")
+	}
+}
@@ -0,0 +1,291 @@
+package cuckoo
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/spaolacci/murmur3"
+	"math"
+	"math/rand"
+	"time"
+)
+
+//Cuckoo filter: svaki kljuc cuva kratak otisak (fingerprint) u jednom od dva moguca bucket-a
+//Drugi bucket se racuna iz prvog i otiska (i2 = hash(otisak) - i1 mod broj bucket-a), pa se otisak moze
+//premestiti bez poznavanja kljuca. Za razliku od bloom filtera podrzava brisanje
+type CuckooFilter struct {
+	buckets uint64 //broj bucket-a
+	bits    uint   //broj bitova otiska
+	seed    uint32
+	count   uint64
+	slots   []uint64 //buckets*bucketSize otisaka spakovanih po bits bitova
+	victim  uint16   //otisak koji nije mogao da se smesti, filter je tada pun
+	victimI uint64
+	full    bool
+}
+
+const (
+	bucketSize = 4
+	maxKicks   = 500
+	loadFactor = 0.95
+	//Zaglavlje na disku: buckets 8B, bits 1B, seed 4B, count 8B, full 1B, victim 2B, victimI 8B
+	headerSize = 32
+)
+
+var ErrCorrupted = errors.New("cuckoo filter corrupted")
+
+//Pravi prazan filter za ocekivani broj kljuceva i zeljenu stopu laznih pozitiva
+//Otisak ima log2(2*bucketSize/fp) bitova, izmedju 4 i 16
+func New(expectedElements int, falsePositiveRate float64) *CuckooFilter {
+	if expectedElements < 1 {
+		expectedElements = 1
+	}
+	bits := uint(math.Ceil(math.Log2(2 * bucketSize / falsePositiveRate)))
+	if bits < 4 {
+		bits = 4
+	}
+	if bits > 16 {
+		bits = 16
+	}
+	buckets := uint64(math.Ceil(float64(expectedElements) / (bucketSize * loadFactor)))
+	return newFilter(buckets, bits, uint32(time.Now().UnixNano()))
+}
+
+func newFilter(buckets uint64, bits uint, seed uint32) *CuckooFilter {
+	return &CuckooFilter{
+		buckets: buckets,
+		bits:    bits,
+		seed:    seed,
+		slots:   make([]uint64, (buckets*bucketSize*uint64(bits)+63)/64),
+	}
+}
+
+//Pravi filter od svih kljuceva, ako se neki ne moze smestiti filter se pravi sa duplo vise bucket-a
+func NewFromKeys(keys []string, falsePositiveRate float64) *CuckooFilter {
+	filter := New(len(keys), falsePositiveRate)
+	for {
+		ok := true
+		for _, key := range keys {
+			if !filter.Add(key) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return filter
+		}
+		filter = newFilter(filter.buckets*2, filter.bits, filter.seed)
+	}
+}
+
+//Indeks prvog bucket-a i otisak kljuca, otisak 0 oznacava prazno mesto
+func (filter *CuckooFilter) locate(key string) (uint64, uint16) {
+	h := murmur3.Sum64WithSeed([]byte(key), filter.seed)
+	fingerprint := uint16((h >> 32) & (1<<filter.bits - 1))
+	if fingerprint == 0 {
+		fingerprint = 1
+	}
+	return h % filter.buckets, fingerprint
+}
+
+//Drugi bucket otiska, alternate(alternate(i)) = i
+//Umesto xor-a se koristi oduzimanje, kako broj bucket-a ne bi morao biti stepen dvojke
+func (filter *CuckooFilter) alternate(i uint64, fingerprint uint16) uint64 {
+	bytes := []byte{byte(fingerprint), byte(fingerprint >> 8)}
+	h := uint64(murmur3.Sum32WithSeed(bytes, filter.seed)) % filter.buckets
+	return (h + filter.buckets - i) % filter.buckets
+}
+
+func (filter *CuckooFilter) get(slot uint64) uint16 {
+	bit := slot * uint64(filter.bits)
+	word, offset := bit/64, bit%64
+	value := filter.slots[word] >> offset
+	if offset+uint64(filter.bits) > 64 {
+		value |= filter.slots[word+1] << (64 - offset)
+	}
+	return uint16(value & (1<<filter.bits - 1))
+}
+
+func (filter *CuckooFilter) set(slot uint64, fingerprint uint16) {
+	bit := slot * uint64(filter.bits)
+	word, offset := bit/64, bit%64
+	mask := uint64(1)<<filter.bits - 1
+	filter.slots[word] = filter.slots[word]&^(mask<<offset) | uint64(fingerprint)<<offset
+	if offset+uint64(filter.bits) > 64 {
+		shift := 64 - offset
+		filter.slots[word+1] = filter.slots[word+1]&^(mask>>shift) | uint64(fingerprint)>>shift
+	}
+}
+
+//Upisuje otisak na prazno mesto u bucket-u
+func (filter *CuckooFilter) insert(i uint64, fingerprint uint16) bool {
+	for j := uint64(0); j < bucketSize; j++ {
+		if filter.get(i*bucketSize+j) == 0 {
+			filter.set(i*bucketSize+j, fingerprint)
+			return true
+		}
+	}
+	return false
+}
+
+func (filter *CuckooFilter) contains(i uint64, fingerprint uint16) bool {
+	for j := uint64(0); j < bucketSize; j++ {
+		if filter.get(i*bucketSize+j) == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+//Dodaje kljuc, vraca false ako je filter pun
+//Kada je pun, kljuc je ipak zapamcen (kao victim), pa MayContain i dalje nema laznih negativa
+func (filter *CuckooFilter) Add(key string) bool {
+	if filter.full {
+		return false
+	}
+	i1, fingerprint := filter.locate(key)
+	i2 := filter.alternate(i1, fingerprint)
+	if filter.insert(i1, fingerprint) || filter.insert(i2, fingerprint) {
+		filter.count++
+		return true
+	}
+	//Izbacujemo nasumican otisak iz bucket-a i premestamo ga u njegov drugi bucket
+	i := i1
+	if rand.Intn(2) == 1 {
+		i = i2
+	}
+	for kick := 0; kick < maxKicks; kick++ {
+		slot := i*bucketSize + uint64(rand.Intn(bucketSize))
+		evicted := filter.get(slot)
+		filter.set(slot, fingerprint)
+		fingerprint = evicted
+		i = filter.alternate(i, fingerprint)
+		if filter.insert(i, fingerprint) {
+			filter.count++
+			return true
+		}
+	}
+	filter.full = true
+	filter.victim = fingerprint
+	filter.victimI = i
+	filter.count++
+	return false
+}
+
+//Proverava da li kljuc mozda postoji u filteru
+func (filter *CuckooFilter) MayContain(key string) bool {
+	i1, fingerprint := filter.locate(key)
+	i2 := filter.alternate(i1, fingerprint)
+	if filter.full && filter.victim == fingerprint && (filter.victimI == i1 || filter.victimI == i2) {
+		return true
+	}
+	return filter.contains(i1, fingerprint) || filter.contains(i2, fingerprint)
+}
+
+//Brise kljuc iz filtera, sme se pozvati samo za kljuc koji je dodat
+func (filter *CuckooFilter) Delete(key string) bool {
+	i1, fingerprint := filter.locate(key)
+	i2 := filter.alternate(i1, fingerprint)
+	if filter.full && filter.victim == fingerprint && (filter.victimI == i1 || filter.victimI == i2) {
+		filter.full = false
+		filter.victim = 0
+		filter.count--
+		return true
+	}
+	for _, i := range []uint64{i1, i2} {
+		for j := uint64(0); j < bucketSize; j++ {
+			if filter.get(i*bucketSize+j) == fingerprint {
+				filter.set(i*bucketSize+j, 0)
+				filter.count--
+				if filter.full {
+					//Oslobodjeno je mesto, victim se vraca u tabelu
+					filter.full = false
+					victim, victimI := filter.victim, filter.victimI
+					filter.victim = 0
+					filter.count--
+					filter.reinsert(victimI, victim)
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (filter *CuckooFilter) reinsert(i uint64, fingerprint uint16) {
+	if filter.insert(i, fingerprint) || filter.insert(filter.alternate(i, fingerprint), fingerprint) {
+		filter.count++
+		return
+	}
+	filter.full = true
+	filter.victim = fingerprint
+	filter.victimI = i
+	filter.count++
+}
+
+//Broj kljuceva u filteru
+func (filter *CuckooFilter) Count() uint64 {
+	return filter.count
+}
+
+//Velicina filtera u memoriji u bajtovima
+func (filter *CuckooFilter) Size() int {
+	return 8 * len(filter.slots)
+}
+
+func (filter *CuckooFilter) Marshal() []byte {
+	bytes := make([]byte, headerSize+8*len(filter.slots))
+	binary.LittleEndian.PutUint64(bytes[0:8], filter.buckets)
+	bytes[8] = byte(filter.bits)
+	binary.LittleEndian.PutUint32(bytes[9:13], filter.seed)
+	binary.LittleEndian.PutUint64(bytes[13:21], filter.count)
+	if filter.full {
+		bytes[21] = 1
+	}
+	binary.LittleEndian.PutUint16(bytes[22:24], filter.victim)
+	binary.LittleEndian.PutUint64(bytes[24:32], filter.victimI)
+	offset := headerSize
+	for _, word := range filter.slots {
+		binary.LittleEndian.PutUint64(bytes[offset:], word)
+		offset += 8
+	}
+	return bytes
+}
+
+func Unmarshal(bytes []byte) (*CuckooFilter, error) {
+	if len(bytes) < headerSize {
+		return nil, ErrCorrupted
+	}
+	buckets := binary.LittleEndian.Uint64(bytes[0:8])
+	bits := uint(bytes[8])
+	if buckets == 0 || bits < 4 || bits > 16 || bytes[21] > 1 ||
+		buckets > uint64(len(bytes))*64/bucketSize/uint64(bits) {
+		return nil, ErrCorrupted
+	}
+	filter := newFilter(buckets, bits, binary.LittleEndian.Uint32(bytes[9:13]))
+	if len(bytes) != headerSize+8*len(filter.slots) {
+		return nil, ErrCorrupted
+	}
+	filter.count = binary.LittleEndian.Uint64(bytes[13:21])
+	filter.full = bytes[21] == 1
+	filter.victim = binary.LittleEndian.Uint16(bytes[22:24])
+	filter.victimI = binary.LittleEndian.Uint64(bytes[24:32])
+	if filter.victimI >= buckets {
+		return nil, ErrCorrupted
+	}
+	offset := headerSize
+	for i := range filter.slots {
+		filter.slots[i] = binary.LittleEndian.Uint64(bytes[offset:])
+		offset += 8
+	}
+	return filter, nil
+}
+
+//Ucitava zapis u postojecu vrednost (interfejs filter.Filter)
+func (filter *CuckooFilter) Unmarshal(bytes []byte) error {
+	loaded, err := Unmarshal(bytes)
+	if err != nil {
+		return err
+	}
+	*filter = *loaded
+	return nil
+}
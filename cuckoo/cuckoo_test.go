@@ -0,0 +1,120 @@
+package cuckoo
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+//Puni filter dok Add ne vrati false, dodati kljucevi (i onaj koji je ostao kao victim) se uvek nalaze
+func fill(t *testing.T, filter *CuckooFilter) []string {
+	t.Helper()
+	keys := make([]string, 0)
+	for i := 0; ; i++ {
+		key := "k" + strconv.Itoa(i)
+		keys = append(keys, key)
+		if !filter.Add(key) {
+			break
+		}
+		if i > 10*int(filter.buckets)*bucketSize {
+			t.Fatalf("filter nije pun posle %d kljuceva", i)
+		}
+	}
+	if filter.Add("jos jedan") {
+		t.Fatal("pun filter je prihvatio kljuc")
+	}
+	if filter.Count() != uint64(len(keys)) {
+		t.Fatalf("ocekivano %d kljuceva, dobijeno %d", len(keys), filter.Count())
+	}
+	return keys
+}
+
+func TestAddUntilFull(t *testing.T) {
+	filter := New(100, 0.01)
+	keys := fill(t, filter)
+	if len(keys) < 100 {
+		t.Fatalf("filter za 100 kljuceva se napunio posle %d", len(keys))
+	}
+	for _, key := range keys {
+		if !filter.MayContain(key) {
+			t.Fatalf("lazno negativan %s", key)
+		}
+	}
+}
+
+//Posle brisanja iz punog filtera victim se vraca u tabelu, a preostali kljucevi se i dalje nalaze
+func TestDelete(t *testing.T) {
+	filter := New(100, 0.01)
+	keys := fill(t, filter)
+	for i, key := range keys {
+		if i%2 == 0 {
+			continue
+		}
+		if !filter.Delete(key) {
+			t.Fatalf("brisanje %s nije uspelo", key)
+		}
+	}
+	if filter.Count() != uint64((len(keys)+1)/2) {
+		t.Fatalf("ocekivano %d kljuceva, dobijeno %d", (len(keys)+1)/2, filter.Count())
+	}
+	for i, key := range keys {
+		if i%2 == 0 && !filter.MayContain(key) {
+			t.Fatalf("lazno negativan %s posle brisanja", key)
+		}
+	}
+	if !filter.Add("novi") || !filter.MayContain("novi") {
+		t.Fatal("kljuc nije dodat posle brisanja")
+	}
+}
+
+//Nema laznih negativa, a stopa laznih pozitiva je blizu trazene
+func TestNoFalseNegatives(t *testing.T) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(i)
+	}
+	filter := NewFromKeys(keys, 0.01)
+	for _, key := range keys {
+		if !filter.MayContain(key) {
+			t.Fatalf("lazno negativan %s", key)
+		}
+	}
+	positives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.MayContain("x" + strconv.Itoa(i)) {
+			positives++
+		}
+	}
+	if positives > 300 {
+		t.Fatalf("ocekivano oko 1%% laznih pozitiva, dobijeno %d od 10000", positives)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	filter := New(100, 0.01)
+	keys := fill(t, filter)
+	for _, full := range []bool{true, false} {
+		if !full {
+			filter.Delete(keys[0])
+		}
+		bytes := filter.Marshal()
+		loaded, err := Unmarshal(bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, filter) {
+			t.Fatalf("filter (pun: %v) se menja serijalizacijom", full)
+		}
+		if _, err := Unmarshal(bytes[:len(bytes)-1]); err != ErrCorrupted {
+			t.Fatalf("ocekivano %v za odsecen zapis, dobijeno %v", ErrCorrupted, err)
+		}
+		if _, err := Unmarshal(bytes[:headerSize-1]); err != ErrCorrupted {
+			t.Fatalf("ocekivano %v za odsecen zapis, dobijeno %v", ErrCorrupted, err)
+		}
+	}
+	corrupted := filter.Marshal()
+	corrupted[8] = 17
+	if _, err := Unmarshal(corrupted); err != ErrCorrupted {
+		t.Fatalf("ocekivano %v za 17 bitova otiska, dobijeno %v", ErrCorrupted, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"kv/SSTable"
+	"net"
+	"os"
+	"strings"
+)
+
+//Pogled na bazu instance koja radi - SSTabele i memtabela, pravi se dok se drzi brava sistema
+func (sys *System) storeView() (*SSTable.StoreView, error) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	view, err := SSTable.LoadStoreView("data")
+	if err != nil {
+		return nil, err
+	}
+	view.Overlay(sys.memtable.structure.GetAll())
+	return view, nil
+}
+
+/*Izlaze pogled na bazu na lokalnom socket-u, da bi diff mogao da poredi instance koje rade
+Prazna putanja ne pokrece nista. Vraca funkciju koja zatvara socket*/
+func (sys *System) serveDiff(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+	//Socket koji je ostao od instance koja nije uredno ugasena se uklanja, ali ne i obican fajl
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New(path + " postoji i nije socket")
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	go SSTable.ServeDiff(listener, sys.storeView)
+	return func() { listener.Close() }, nil
+}
+
+//Otvara bazu za diff: data direktorijum ili unix:<putanja> instance koja radi
+func openStore(name string) (SSTable.StoreSource, func(), error) {
+	if strings.HasPrefix(name, "unix:") {
+		remote, err := SSTable.DialStore(strings.TrimPrefix(name, "unix:"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return remote, func() { remote.Close() }, nil
+	}
+	view, err := SSTable.LoadStoreView(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return view, func() {}, nil
+}
+
+func diffSources(first string, second string) ([]SSTable.KeyDiff, error) {
+	firstStore, closeFirst, err := openStore(first)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFirst()
+	secondStore, closeSecond, err := openStore(second)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSecond()
+	return SSTable.DiffSources(firstStore, secondStore)
+}
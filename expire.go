@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+/*Vreme isteka kljuca (SET EX/PX) se cuva pod rezervisanim kljucem, kao 8 bajtova unix milisekundi
+Upisuje se kroz WAL kao i sama vrednost, pa prezivljava i pad, a ne samo uredno gasenje
+Indeks se gradi iz tih zapisa prefiksnim skeniranjem pri prvoj upotrebi. Obican upis (put), brisanje i pravljenje nove
+vrednosti sa tipom brisu vreme isteka kljuca (dropExpire) iz bilo kog dela sistema, pa staro vreme ne brise novu vrednost
+Istekao kljuc se brise kroz Delete pri citanju (get), a skeniranja ga ne vracaju ni pre nego sto se obrise*/
+const expirePrefix = reservedPrefix + "expire/"
+
+func (sys *System) expireIndex() map[string]time.Time {
+	if sys.expires == nil {
+		sys.expires = make(map[string]time.Time)
+		for _, kv := range sys.reservedScan(expirePrefix) {
+			if len(kv.value) == 8 {
+				millis := int64(binary.LittleEndian.Uint64(kv.value))
+				sys.expires[strings.TrimPrefix(kv.key, expirePrefix)] = time.Unix(0, millis*int64(time.Millisecond))
+			}
+		}
+	}
+	return sys.expires
+}
+
+//Upisuje vreme isteka postojeceg kljuca
+func (sys *System) setExpire(key string, deadline time.Time) bool {
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, uint64(deadline.UnixNano()/int64(time.Millisecond)))
+	if !sys.write(expirePrefix+key, bytes) {
+		return false
+	}
+	sys.expireIndex()[key] = deadline
+	return true
+}
+
+//Brise vreme isteka kljuca, poziva se posle svakog upisa i brisanja korisnickog kljuca
+func (sys *System) dropExpire(key string) {
+	if isReservedKey(key) {
+		return
+	}
+	index := sys.expireIndex()
+	if _, found := index[key]; found {
+		delete(index, key)
+		sys.Delete("", expirePrefix+key)
+	}
+}
+
+//Da li je kljucu isteklo vreme, bez brisanja
+func (sys *System) isExpired(key string) bool {
+	deadline, found := sys.expireIndex()[key]
+	return found && !time.Now().Before(deadline)
+}
+
+//Brise kljuc ako mu je isteklo vreme i vraca da li je obrisan
+func (sys *System) expire(key string) bool {
+	if !sys.isExpired(key) {
+		return false
+	}
+	sys.Delete("", key)
+	sys.expired++
+	return true
+}
+
+//Brise sve kljuceve kojima je isteklo vreme
+func (sys *System) expireDue() {
+	for key := range sys.expireIndex() {
+		sys.expire(key)
+	}
+}
@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"kv/bloom"
+	"kv/cuckoo"
+)
+
+//Zajednicki interfejs filtera SSTabele
+//Unmarshal ucitava filter u postojecu (praznu) vrednost istog tipa
+type Filter interface {
+	Add(key string) bool
+	MayContain(key string) bool
+	Marshal() []byte
+	Unmarshal(bytes []byte) error
+	Size() int
+}
+
+//Tip filtera, upisuje se u zaglavlje fajla filtera
+type Type byte
+
+const (
+	Bloom  Type = 1
+	Cuckoo Type = 2
+)
+
+//Zaglavlje fajla filtera: "FLTR" pa tip 1B
+//Fajlovi bez zaglavlja su bloom filteri napravljeni pre uvodjenja tipova
+const magic = "FLTR"
+
+var ErrUnknownType = errors.New("unknown filter type")
+
+func ParseType(name string) (Type, error) {
+	switch name {
+	case "bloom":
+		return Bloom, nil
+	case "cuckoo":
+		return Cuckoo, nil
+	}
+	return 0, fmt.Errorf("%w %q (bloom or cuckoo)", ErrUnknownType, name)
+}
+
+func (t Type) String() string {
+	switch t {
+	case Bloom:
+		return "bloom"
+	case Cuckoo:
+		return "cuckoo"
+	}
+	return "unknown"
+}
+
+//Prazan filter zadatog tipa, u koji se moze ucitati zapis sa Unmarshal
+func empty(t Type) (Filter, error) {
+	switch t {
+	case Bloom:
+		return &bloom.BloomFilter{}, nil
+	case Cuckoo:
+		return &cuckoo.CuckooFilter{}, nil
+	}
+	return nil, ErrUnknownType
+}
+
+//Pravi filter zadatog tipa nad kljucevima
+func New(t Type, keys []string, falsePositiveRate float64) Filter {
+	if t == Cuckoo {
+		return cuckoo.NewFromKeys(keys, falsePositiveRate)
+	}
+	return bloom.NewBloom(keys, falsePositiveRate)
+}
+
+func TypeOf(f Filter) Type {
+	if _, ok := f.(*cuckoo.CuckooFilter); ok {
+		return Cuckoo
+	}
+	return Bloom
+}
+
+//Binarni zapis filtera sa zaglavljem tipa
+func Marshal(f Filter) []byte {
+	return append(append([]byte(magic), byte(TypeOf(f))), f.Marshal()...)
+}
+
+func Unmarshal(data []byte) (Filter, error) {
+	t, payload := Bloom, data
+	if len(data) > len(magic) && bytes.Equal(data[:len(magic)], []byte(magic)) {
+		t, payload = Type(data[len(magic)]), data[len(magic)+1:]
+	}
+	f, err := empty(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func path(name string) string {
+	return "data/SSTable" + name + "/filter" + name + ".txt"
+}
+
+func Write(f Filter, name string) error {
+	return ioutil.WriteFile(path(name), Marshal(f), 0666)
+}
+
+func Load(name string) (Filter, error) {
+	data, err := ioutil.ReadFile(path(name))
+	if err != nil {
+		return nil, err
+	}
+	f, err := Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("filter of SSTable%s: %w", name, err)
+	}
+	return f, nil
+}
@@ -1,4 +1,6 @@
-module main
+// Modul se ranije zvao "main". Od preimenovanja u "kv" uvozne putanje paketa su kv/<paket>
+// (npr. kv/SSTable umesto main/SSTable), a "go build" pravi izvrsni fajl kv umesto main
+module kv
 
 go 1.17
 
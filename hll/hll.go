@@ -0,0 +1,121 @@
+package hll
+
+import (
+	"errors"
+	"github.com/spaolacci/murmur3"
+	"math"
+	"math/bits"
+)
+
+//HyperLogLog procena broja razlicitih elemenata
+//Prvih p bitova 64-bitnog hash-a bira registar, a registar pamti najveci broj vodecih nula
+//ostatka hash-a (+1). Greska procene je oko 1.04/sqrt(2^p)
+type HLL struct {
+	p         uint8
+	registers []uint8
+}
+
+const (
+	MinPrecision = 4
+	MaxPrecision = 16
+)
+
+var (
+	ErrPrecision    = errors.New("hll precision must be between 4 and 16")
+	ErrCorrupted    = errors.New("hll sketch corrupted")
+	ErrIncompatible = errors.New("hll sketches have different precision")
+)
+
+func New(p uint8) (*HLL, error) {
+	if p < MinPrecision || p > MaxPrecision {
+		return nil, ErrPrecision
+	}
+	return &HLL{p: p, registers: make([]uint8, 1<<p)}, nil
+}
+
+func (hll *HLL) Precision() uint8 {
+	return hll.p
+}
+
+func (hll *HLL) Add(item string) {
+	h := murmur3.Sum64([]byte(item))
+	index := h >> (64 - hll.p)
+	//Postavljen bit ispod ostatka ogranicava broj nula kada je ostatak hash-a nula
+	rest := h<<hll.p | 1<<(hll.p-1)
+	rank := uint8(bits.LeadingZeros64(rest)) + 1
+	if rank > hll.registers[index] {
+		hll.registers[index] = rank
+	}
+}
+
+//Procena broja razlicitih dodatih elemenata
+func (hll *HLL) Estimate() uint64 {
+	m := float64(len(hll.registers))
+	sum := 0.0
+	empty := 0
+	for _, register := range hll.registers {
+		sum += math.Pow(2, -float64(register))
+		if register == 0 {
+			empty++
+		}
+	}
+	estimate := alpha(len(hll.registers)) * m * m / sum
+	//Za male kardinalnosti je brojanje praznih registara (linear counting) preciznije
+	if estimate <= 2.5*m && empty > 0 {
+		estimate = m * math.Log(m/float64(empty))
+	}
+	return uint64(estimate + 0.5)
+}
+
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
+
+//Spaja drugu skicu u ovu - rezultat procenjuje broj elemenata unije
+func (hll *HLL) Merge(other *HLL) error {
+	if hll.p != other.p {
+		return ErrIncompatible
+	}
+	for i, register := range other.registers {
+		if register > hll.registers[i] {
+			hll.registers[i] = register
+		}
+	}
+	return nil
+}
+
+//Binarni zapis: preciznost 1B, pa registri po 1B
+func (hll *HLL) Marshal() []byte {
+	bytes := make([]byte, 1+len(hll.registers))
+	bytes[0] = hll.p
+	copy(bytes[1:], hll.registers)
+	return bytes
+}
+
+func Unmarshal(bytes []byte) (*HLL, error) {
+	if len(bytes) < 1 {
+		return nil, ErrCorrupted
+	}
+	hll, err := New(bytes[0])
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	if len(bytes) != 1+len(hll.registers) {
+		return nil, ErrCorrupted
+	}
+	for i, register := range bytes[1:] {
+		if register > 64-hll.p+1 {
+			return nil, ErrCorrupted
+		}
+		hll.registers[i] = register
+	}
+	return hll, nil
+}
@@ -0,0 +1,88 @@
+package hll
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func sketch(t *testing.T, p uint8, from, to int) *HLL {
+	t.Helper()
+	hll, err := New(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := from; i < to; i++ {
+		hll.Add("k" + strconv.Itoa(i))
+	}
+	return hll
+}
+
+//Procena poznatog broja razlicitih elemenata je unutar tri standardne greske 1.04/sqrt(2^p),
+//a ponovljeni elementi ne menjaju procenu
+func TestErrorBound(t *testing.T) {
+	for _, p := range []uint8{MinPrecision, 10, 14, MaxPrecision} {
+		bound := 3 * 1.04 / math.Sqrt(float64(uint64(1)<<p))
+		for _, n := range []int{100, 10000, 200000} {
+			hll := sketch(t, p, 0, n)
+			estimate := hll.Estimate()
+			if deviation := math.Abs(float64(estimate)-float64(n)) / float64(n); deviation > bound {
+				t.Errorf("p=%d, n=%d: procena %d, odstupanje %.4f preko %.4f", p, n, estimate, deviation, bound)
+			}
+			for i := 0; i < n; i++ {
+				hll.Add("k" + strconv.Itoa(i))
+			}
+			if hll.Estimate() != estimate {
+				t.Errorf("p=%d, n=%d: ponovljeni elementi promenili procenu sa %d na %d", p, n, estimate, hll.Estimate())
+			}
+		}
+	}
+	if _, err := New(MinPrecision - 1); err != ErrPrecision {
+		t.Fatalf("ocekivano %v, dobijeno %v", ErrPrecision, err)
+	}
+	if _, err := New(MaxPrecision + 1); err != ErrPrecision {
+		t.Fatalf("ocekivano %v, dobijeno %v", ErrPrecision, err)
+	}
+}
+
+//Spajanje skica sa preklapanjem je isto kao jedna skica unije
+func TestMerge(t *testing.T) {
+	first := sketch(t, 12, 0, 60000)
+	second := sketch(t, 12, 40000, 100000)
+	if err := first.Merge(second); err != nil {
+		t.Fatal(err)
+	}
+	union := sketch(t, 12, 0, 100000)
+	if !reflect.DeepEqual(first, union) {
+		t.Fatalf("spojena skica procenjuje %d, skica unije %d", first.Estimate(), union.Estimate())
+	}
+	if err := first.Merge(sketch(t, 10, 0, 10)); err != ErrIncompatible {
+		t.Fatalf("ocekivano %v, dobijeno %v", ErrIncompatible, err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	hll := sketch(t, 10, 0, 5000)
+	bytes := hll.Marshal()
+	loaded, err := Unmarshal(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, hll) || loaded.Estimate() != hll.Estimate() {
+		t.Fatal("skica se menja serijalizacijom")
+	}
+
+	corrupted := [][]byte{
+		nil,
+		bytes[:len(bytes)-1],
+		append(append([]byte{}, bytes...), 0),
+		append([]byte{MaxPrecision + 1}, bytes[1:]...),
+		append([]byte{bytes[0]}, append([]byte{64 - 10 + 2}, bytes[2:]...)...),
+	}
+	for i, data := range corrupted {
+		if _, err := Unmarshal(data); err != ErrCorrupted {
+			t.Errorf("zapis %d: ocekivano %v, dobijeno %v", i, ErrCorrupted, err)
+		}
+	}
+}
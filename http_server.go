@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//Zaglavlje sa korisnikom ciji se tokeni trose, bez njega zahtev nije ogranicen
+const userHeader = "X-User"
+
+//Najveca velicina tela zahteva
+const maxBodyBytes = 16 << 20
+
+//Koliko se pri gasenju ceka da se zapoceti zahtevi zavrse
+const shutdownTimeout = 10 * time.Second
+
+/*REST server nad sistemom
+GET/PUT/DELETE /kv/{kljuc} - citanje, upis i brisanje jednog kljuca
+GET /kv?prefix=&start=&end=&limit= - kljucevi sa prefiksom ili iz opsega [start, end)
+POST /batch - niz operacija koje se izvrsavaju redom, bez upliva drugih zahteva
+PUT/POST/GET /bloom/{kljuc} - pravljenje bloom filtera, dodavanje elemenata i provera elementa (http_values.go)
+PUT/POST/GET /hll/{kljuc} - pravljenje HyperLogLog skice, dodavanje elemenata ili spajanje skica i procena broja elemenata
+PUT/POST/GET /cms/{kljuc} - pravljenje Count-Min skice, dodavanje pojavljivanja ili sabiranje skica i procena broja pojavljivanja
+PUT /doc/{kljuc} - upis dokumenta sa SimHash otiskom, GET /doc?text=&distance= - dokumenti slicni tekstu
+Vrednosti su u JSON-u kodirane base64, pa binarne vrednosti (i vrednosti sa tipom) stizu neizmenjene
+Svaka operacija nad podacima trosi jedan token korisnika iz zaglavlja X-User*/
+type httpServer struct {
+	sys *System
+}
+
+type jsonKV struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type batchOp struct {
+	Op    string `json:"op"` //put, get ili delete
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type batchResult struct {
+	Key   string  `json:"key"`
+	Found *bool   `json:"found,omitempty"` //za get i delete
+	Value *[]byte `json:"value,omitempty"` //za pronadjen kljuc u get
+}
+
+//Zahtev je odbijen jer korisnik nema vise tokena
+type rateLimitError struct {
+	user        string
+	retry_after uint64
+}
+
+func (err rateLimitError) Error() string {
+	return "korisnik " + err.user + " nema vise tokena"
+}
+
+//Komanda serve [--addr adresa] [--diff-socket putanja] - pokrece REST server, SIGINT ili SIGTERM ga uredno gase
+func serveHTTP(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "adresa na kojoj server slusa")
+	diffSocket := flags.String("diff-socket", "", "lokalni socket na kome se izlaze baza za diff")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Println("upotreba: serve [--addr adresa] [--diff-socket putanja]")
+		return 2
+	}
+	if !openSystem() {
+		return 1
+	}
+	stopDiff, err := system.serveDiff(*diffSocket)
+	if err != nil {
+		fmt.Println(err)
+		closeSystem()
+		return 1
+	}
+	server := &http.Server{Addr: *addr, Handler: &httpServer{sys: system}}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan error, 1)
+	go func() {
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		done <- server.Shutdown(ctx)
+	}()
+
+	fmt.Println("REST server slusa na " + *addr)
+	status := 0
+	err = server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		err = <-done
+	}
+	if err != nil {
+		fmt.Println(err)
+		status = 1
+	}
+	//Tek kada se svi zahtevi zavrse upisuje se ostatak WAL-a
+	stopDiff()
+	if closeSystem() != 0 {
+		status = 1
+	}
+	return status
+}
+
+func (server *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/kv/"):
+		key, correct := pathKey(w, r, "/kv/")
+		if !correct {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			server.handleGet(w, r, key)
+		case http.MethodPut:
+			server.handlePut(w, r, key)
+		case http.MethodDelete:
+			server.handleDelete(w, r, key)
+		default:
+			methodNotAllowed(w, "GET, PUT, DELETE")
+		}
+	case r.URL.Path == "/kv":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		server.handleScan(w, r)
+	case strings.HasPrefix(r.URL.Path, "/bloom/"):
+		key, correct := pathKey(w, r, "/bloom/")
+		if correct {
+			server.handleBloom(w, r, key)
+		}
+	case strings.HasPrefix(r.URL.Path, "/hll/"):
+		key, correct := pathKey(w, r, "/hll/")
+		if correct {
+			server.handleHLL(w, r, key)
+		}
+	case strings.HasPrefix(r.URL.Path, "/cms/"):
+		key, correct := pathKey(w, r, "/cms/")
+		if correct {
+			server.handleCMS(w, r, key)
+		}
+	case strings.HasPrefix(r.URL.Path, "/doc/"):
+		key, correct := pathKey(w, r, "/doc/")
+		if correct {
+			server.handleDocument(w, r, key)
+		}
+	case r.URL.Path == "/doc":
+		server.handleSimilar(w, r)
+	case r.URL.Path == "/batch":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		server.handleBatch(w, r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("nepostojeca putanja "+r.URL.Path))
+	}
+}
+
+//Kljuc iz putanje posle prefiksa, ako nije ispravan odgovara se greskom i vraca false
+func pathKey(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("kljuc ne sme biti prazan"))
+		return "", false
+	}
+	if err := checkUserKey(key); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	return key, true
+}
+
+func (server *httpServer) handleGet(w http.ResponseWriter, r *http.Request, key string) {
+	sys := server.sys
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	if err := chargeRequest(r, 1); err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	value := sys.get("", key)
+	if value == nil {
+		writeError(w, http.StatusNotFound, errors.New("kljuc "+key+" ne postoji"))
+		return
+	}
+	writeJSON(w, http.StatusOK, jsonKV{Key: key, Value: value})
+}
+
+//Telo zahteva je {"value": "..."}, vrednost kodirana base64
+func (server *httpServer) handlePut(w http.ResponseWriter, r *http.Request, key string) {
+	var body struct {
+		Value *[]byte `json:"value"`
+	}
+	if err := decodeBody(w, r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Value == nil {
+		writeError(w, http.StatusBadRequest, errors.New("nedostaje value"))
+		return
+	}
+	sys := server.sys
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	if err := chargeRequest(r, 1); err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if !sys.put("", key, *body.Value) {
+		writeError(w, http.StatusInternalServerError, errors.New("upis nije uspeo"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *httpServer) handleDelete(w http.ResponseWriter, r *http.Request, key string) {
+	sys := server.sys
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	if err := chargeRequest(r, 1); err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if !sys.Delete("", key) {
+		writeError(w, http.StatusNotFound, errors.New("kljuc "+key+" ne postoji"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//prefix se ne moze kombinovati sa start i end, bez ijednog vracaju se svi kljucevi
+func (server *httpServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, start, end := query.Get("prefix"), query.Get("start"), query.Get("end")
+	if prefix != "" && (start != "" || end != "") {
+		writeError(w, http.StatusBadRequest, errors.New("prefix se ne moze kombinovati sa start i end"))
+		return
+	}
+	limit := 0
+	if query.Get("limit") != "" {
+		correct, val := CheckValInt(query.Get("limit"), 1, 1000000)
+		if !correct {
+			writeError(w, http.StatusBadRequest, errors.New("limit mora biti ceo broj od 1 do 1000000"))
+			return
+		}
+		limit = val
+	}
+
+	sys := server.sys
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	if err := chargeRequest(r, 1); err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	var kvs []KV
+	if prefix != "" {
+		kvs = sys.PrefixScan("", prefix)
+		if limit > 0 && len(kvs) > limit {
+			kvs = kvs[:limit]
+		}
+	} else {
+		kvs = sys.RangeScan("", start, end, limit)
+	}
+	items := make([]jsonKV, len(kvs))
+	for i, kv := range kvs {
+		items[i] = jsonKV{Key: kv.key, Value: kv.value}
+	}
+	writeJSON(w, http.StatusOK, map[string][]jsonKV{"items": items})
+}
+
+/*Telo zahteva je {"ops": [{"op": "put", "key": "...", "value": "..."}, {"op": "get", "key": "..."}, ...]}
+Operacije se proveravaju pre izvrsavanja, pa neispravan zahtev ne menja nista
+Svaka operacija trosi jedan token i svi se uzimaju unapred - ako ih nema dovoljno, nijedna operacija se ne izvrsava
+Zahtev sa vise operacija nego sto baket ima tokena se odbija sa 413, jer ga ni pun baket ne bi pokrio*/
+func (server *httpServer) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Ops []batchOp `json:"ops"`
+	}
+	if err := decodeBody(w, r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	for i, op := range body.Ops {
+		if op.Key == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("operacija %d: kljuc ne sme biti prazan", i))
+			return
+		}
+		if err := checkUserKey(op.Key); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("operacija %d: %w", i, err))
+			return
+		}
+		if op.Op != "put" && op.Op != "get" && op.Op != "delete" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("operacija %d: nepoznata operacija %q", i, op.Op))
+			return
+		}
+	}
+
+	sys := server.sys
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	if r.Header.Get(userHeader) != "" && len(body.Ops) > int(tokensPerReset) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("zahtev ima %d operacija, a korisnik moze potrositi najvise %d tokena po intervalu", len(body.Ops), tokensPerReset))
+		return
+	}
+	if err := chargeRequest(r, len(body.Ops)); err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	results := make([]batchResult, len(body.Ops))
+	for i, op := range body.Ops {
+		results[i].Key = op.Key
+		switch op.Op {
+		case "put":
+			if !sys.put("", op.Key, op.Value) {
+				writeError(w, http.StatusInternalServerError, fmt.Errorf("operacija %d: upis nije uspeo", i))
+				return
+			}
+		case "get":
+			value := sys.get("", op.Key)
+			found := value != nil
+			results[i].Found = &found
+			if found {
+				results[i].Value = &value
+			}
+		case "delete":
+			found := sys.Delete("", op.Key)
+			results[i].Found = &found
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string][]batchResult{"results": results})
+}
+
+//Trosi n tokena korisnika iz zaglavlja, svih n ili nijedan, poziva se dok se drzi brava sistema
+func chargeRequest(r *http.Request, n int) error {
+	user := r.Header.Get(userHeader)
+	if user == "" {
+		return nil
+	}
+	if !TakeTokens(user, uint32(n)) {
+		return rateLimitError{user: user, retry_after: TokenBucketRetryAfter(user, uint32(n))}
+	}
+	return nil
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, body interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(body); err != nil {
+		return fmt.Errorf("neispravno telo zahteva: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	var limited rateLimitError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", strconv.FormatUint(limited.retry_after, 10))
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("nedozvoljena metoda"))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func doHTTP(t *testing.T, server *httpServer, method string, path string, body interface{}, user string) *httptest.ResponseRecorder {
+	t.Helper()
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	if user != "" {
+		request.Header.Set(userHeader, user)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+//Binarne vrednosti (i neispravan UTF-8) se vracaju neizmenjene, kroz GET, scan i batch
+func TestHTTPBinaryValues(t *testing.T) {
+	server := &httpServer{sys: newTestSystem(t)}
+	value := []byte{0, 0xff, 0xfe, 'B', 'L', 'M', 'F', 0x80}
+	if response := doHTTP(t, server, http.MethodPut, "/kv/b", map[string][]byte{"value": value}, ""); response.Code != http.StatusNoContent {
+		t.Fatalf("PUT: %d %s", response.Code, response.Body)
+	}
+
+	var item jsonKV
+	response := doHTTP(t, server, http.MethodGet, "/kv/b", nil, "")
+	if err := json.Unmarshal(response.Body.Bytes(), &item); err != nil || !bytes.Equal(item.Value, value) {
+		t.Fatalf("GET: ocekivano %v, dobijeno %v (%v)", value, item.Value, err)
+	}
+
+	var scan struct {
+		Items []jsonKV `json:"items"`
+	}
+	response = doHTTP(t, server, http.MethodGet, "/kv?prefix=b", nil, "")
+	if err := json.Unmarshal(response.Body.Bytes(), &scan); err != nil || len(scan.Items) != 1 || !bytes.Equal(scan.Items[0].Value, value) {
+		t.Fatalf("scan: dobijeno %s (%v)", response.Body, err)
+	}
+
+	var batch struct {
+		Results []batchResult `json:"results"`
+	}
+	ops := []batchOp{{Op: "put", Key: "c", Value: value}, {Op: "get", Key: "c"}}
+	response = doHTTP(t, server, http.MethodPost, "/batch", map[string][]batchOp{"ops": ops}, "")
+	if err := json.Unmarshal(response.Body.Bytes(), &batch); err != nil || len(batch.Results) != 2 || batch.Results[1].Value == nil || !bytes.Equal(*batch.Results[1].Value, value) {
+		t.Fatalf("batch: dobijeno %s (%v)", response.Body, err)
+	}
+}
+
+//Batch koji trosi vise tokena nego sto baket ima se odbija sa 413 umesto 429, jer mu cekanje ne pomaze
+func TestHTTPBatchLargerThanBucket(t *testing.T) {
+	server := &httpServer{sys: newTestSystem(t)}
+	//Baketi tokena se citaju i pisu kroz globalni sistem
+	previous := system
+	system = server.sys
+	t.Cleanup(func() { system = previous })
+	ops := make([]batchOp, tokensPerReset+1)
+	for i := range ops {
+		ops[i] = batchOp{Op: "get", Key: "k" + strconv.Itoa(i)}
+	}
+	response := doHTTP(t, server, http.MethodPost, "/batch", map[string][]batchOp{"ops": ops}, "pera")
+	if response.Code != http.StatusRequestEntityTooLarge || response.Header().Get("Retry-After") != "" {
+		t.Fatalf("ocekivano 413 bez Retry-After, dobijeno %d %q", response.Code, response.Header().Get("Retry-After"))
+	}
+
+	response = doHTTP(t, server, http.MethodPost, "/batch", map[string][]batchOp{"ops": ops[:tokensPerReset]}, "pera")
+	if response.Code != http.StatusOK {
+		t.Fatalf("ocekivano 200, dobijeno %d %s", response.Code, response.Body)
+	}
+	response = doHTTP(t, server, http.MethodPost, "/batch", map[string][]batchOp{"ops": ops[:1]}, "pera")
+	if response.Code != http.StatusTooManyRequests || !strings.Contains(response.Body.String(), "tokena") {
+		t.Fatalf("ocekivano 429, dobijeno %d %s", response.Code, response.Body)
+	}
+}
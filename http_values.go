@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+//Rute za vrednosti sa tipom (values.go)
+
+//PUT pravi filter ({"expected": n, "fp_rate": p}), POST dodaje elemente ({"items": [...]}), GET ?item= proverava element
+func (server *httpServer) handleBloom(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Expected int     `json:"expected"`
+			FPRate   float64 `json:"fp_rate"`
+		}
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			return nil, sys.BloomCreate("", key, body.Expected, body.FPRate)
+		})
+	case http.MethodPost:
+		var body struct {
+			Items []string `json:"items"`
+		}
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			added, err := sys.BloomAdd("", key, body.Items...)
+			return map[string][]bool{"added": added}, err
+		})
+	case http.MethodGet:
+		item, found := r.URL.Query()["item"]
+		if !found || len(item) != 1 {
+			writeError(w, http.StatusBadRequest, errors.New("potreban je tacno jedan parametar item"))
+			return
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			contains, err := sys.BloomContains("", key, item[0])
+			return map[string]bool{"contains": contains}, err
+		})
+	default:
+		methodNotAllowed(w, "GET, PUT, POST")
+	}
+}
+
+/*Izvrsava operaciju nad vrednoscu sa tipom dok drzi bravu sistema, posle trosenja jednog tokena
+Odgovor je rezultat operacije, ili 204 ako ga nema. Nepostojeci kljuc je 404, vrednost drugog tipa 409*/
+func (server *httpServer) typed(w http.ResponseWriter, r *http.Request, operation func(sys *System) (interface{}, error)) {
+	sys := server.sys
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+	if err := chargeRequest(r, 1); err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	result, err := operation(sys)
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrWrongType):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, errWriteFailed):
+		writeError(w, http.StatusInternalServerError, err)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	case result == nil:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+//PUT pravi skicu ({"precision": p}), POST dodaje elemente ({"items": [...]}) ili spaja skice ({"sources": [...]}), GET vraca procenu
+func (server *httpServer) handleHLL(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Precision uint8 `json:"precision"`
+		}
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			return nil, sys.HLLCreate("", key, body.Precision)
+		})
+	case http.MethodPost:
+		var body struct {
+			Items   []string `json:"items"`
+			Sources []string `json:"sources"`
+		}
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if (len(body.Items) == 0) == (len(body.Sources) == 0) {
+			writeError(w, http.StatusBadRequest, errors.New("potrebno je tacno jedno od items i sources"))
+			return
+		}
+		for _, source := range body.Sources {
+			if err := checkUserKey(source); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			if len(body.Sources) != 0 {
+				return nil, sys.HLLMerge("", key, body.Sources...)
+			}
+			changed, err := sys.HLLAdd("", key, body.Items...)
+			return map[string]bool{"changed": changed}, err
+		})
+	case http.MethodGet:
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			estimate, err := sys.HLLEstimate("", key)
+			return map[string]uint64{"estimate": estimate}, err
+		})
+	default:
+		methodNotAllowed(w, "GET, PUT, POST")
+	}
+}
+
+/*PUT pravi skicu ({"epsilon": e, "delta": d} ili {"like": izvor}), POST dodaje pojavljivanja ({"item": x, "count": n})
+ili sabira skice ({"sources": [...]}), GET ?item= vraca procenu broja pojavljivanja*/
+func (server *httpServer) handleCMS(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Epsilon float64 `json:"epsilon"`
+			Delta   float64 `json:"delta"`
+			Like    string  `json:"like"`
+		}
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := checkUserKey(body.Like); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			if body.Like != "" {
+				return nil, sys.CMSCreateLike("", key, body.Like)
+			}
+			return nil, sys.CMSCreate("", key, body.Epsilon, body.Delta)
+		})
+	case http.MethodPost:
+		var body struct {
+			Item    string   `json:"item"`
+			Count   *uint64  `json:"count"`
+			Sources []string `json:"sources"`
+		}
+		if err := decodeBody(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if (body.Item == "") == (len(body.Sources) == 0) {
+			writeError(w, http.StatusBadRequest, errors.New("potrebno je tacno jedno od item i sources"))
+			return
+		}
+		for _, source := range body.Sources {
+			if err := checkUserKey(source); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		count := uint64(1)
+		if body.Count != nil {
+			count = *body.Count
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			if len(body.Sources) != 0 {
+				return nil, sys.CMSMerge("", key, body.Sources...)
+			}
+			estimate, err := sys.CMSAdd("", key, body.Item, count)
+			return map[string]uint64{"estimate": estimate}, err
+		})
+	case http.MethodGet:
+		item, found := r.URL.Query()["item"]
+		if !found || len(item) != 1 {
+			writeError(w, http.StatusBadRequest, errors.New("potreban je tacno jedan parametar item"))
+			return
+		}
+		server.typed(w, r, func(sys *System) (interface{}, error) {
+			estimate, err := sys.CMSEstimate("", key, item[0])
+			return map[string]uint64{"estimate": estimate}, err
+		})
+	default:
+		methodNotAllowed(w, "GET, PUT, POST")
+	}
+}
+
+//PUT /doc/{kljuc} upisuje dokument i njegov otisak ({"text": "..."})
+func (server *httpServer) handleDocument(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodPut {
+		methodNotAllowed(w, "PUT")
+		return
+	}
+	var body struct {
+		Text *string `json:"text"`
+	}
+	if err := decodeBody(w, r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Text == nil {
+		writeError(w, http.StatusBadRequest, errors.New("nedostaje text"))
+		return
+	}
+	server.typed(w, r, func(sys *System) (interface{}, error) {
+		return nil, sys.PutDocument("", key, *body.Text)
+	})
+}
+
+//GET /doc?text=&distance= vraca dokumente ciji je otisak na udaljenosti najvise distance (3 ako nije zadata) od teksta
+func (server *httpServer) handleSimilar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+	query := r.URL.Query()
+	distance := simhashDistance
+	if query.Get("distance") != "" {
+		correct, val := CheckValInt(query.Get("distance"), 0, 64)
+		if !correct {
+			writeError(w, http.StatusBadRequest, errors.New("distance mora biti ceo broj od 0 do 64"))
+			return
+		}
+		distance = val
+	}
+	server.typed(w, r, func(sys *System) (interface{}, error) {
+		matches, err := sys.NearDuplicates("", query.Get("text"), distance)
+		items := make([]jsonMatch, len(matches))
+		for i, match := range matches {
+			items[i] = jsonMatch{Key: match.Key, Distance: match.Distance}
+		}
+		return map[string][]jsonMatch{"matches": items}, err
+	})
+}
+
+type jsonMatch struct {
+	Key      string `json:"key"`
+	Distance int    `json:"distance"`
+}
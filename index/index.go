@@ -30,14 +30,12 @@ func NewIndex(entrysLen []uint64, name string) {
 	}
 }
 
-func Find(offset uint64, name string) uint64 {
-	file, err := os.Open("data/SSTable" + name + "/index" + name + ".txt")
-	defer file.Close()
+//Cita offset u SSTabeli iz vec otvorenog index fajla
+func FindIn(file *os.File, offset uint64) (uint64, error) {
+	tableOffset := make([]byte, 8)
+	_, err := file.ReadAt(tableOffset, int64(offset))
 	if err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
-	tableOffset := make([]byte, 8)
-	file.Seek(int64(offset), 0)
-	file.Read(tableOffset)
-	return binary.LittleEndian.Uint64(tableOffset)
+	return binary.LittleEndian.Uint64(tableOffset), nil
 }
@@ -3,8 +3,9 @@ package kompakcije
 import (
 	"encoding/binary"
 	"errors"
+	"io"
+	"kv/SSTable"
 	"log"
-	"main/SSTable"
 	"os"
 	"strconv"
 )
@@ -17,36 +18,50 @@ type entry struct {
 	loaded    bool
 }
 
-func Kompakcija(merge int, maxLevel int,bloomPer float64) {
+func Kompakcija(merge int, maxLevel int, bloomPer float64) error {
 	//1 iteration for every level
 	for level := 1; level < maxLevel; level++ {
 		last := FindLastFile(level)
 		//Try to merge until can't
 		for next := true; next; {
-			next = mergeTables(merge, level,bloomPer)
+			var err error
+			next, err = mergeTables(merge, level, bloomPer)
+			if err != nil {
+				//Ostecene tabele se ne brisu, kompakcija se prekida
+				return err
+			}
 			//Delete merged tables
 			if next {
 				tidyLevel(level, merge, last)
 			}
 		}
 	}
+	return nil
 }
 
-func mergeTables(merge int, level int,bloomPer float64) bool {
+func mergeTables(merge int, level int, bloomPer float64) (bool, error) {
 	//Slice of files to merge
 	files, err := loadTables(merge, level)
 	if err {
-		return false
+		return false, nil
 	}
 
 	//Data for the new table
-	newTableData := fillData(files)
+	newTableData, readErr := fillData(files)
 	closeFiles(files)
-	SSTable.MakeTable(newTableData, level+1,bloomPer)
-	return true
+	if readErr != nil {
+		return false, readErr
+	}
+	SSTable.MakeTable(newTableData, level+1, bloomPer)
+	return true, nil
 }
 
-func fillData(files []*os.File) [][]byte {
+/*Spaja zapise iz tabela u jedan sortiran niz i za svaki kljuc zadrzava samo najnoviju verziju
+Citanje pronalazi jedan zapis kljuca u tabeli preko indeksa, pa bi starija verzija u spojenoj tabeli
+mogla da zakloni noviju. Ako je najnovija verzija obrisana (tombstone), MakeTable je ne upisuje, pa
+spojena tabela nema taj kljuc. To je bezbedno jer Delete postavlja tombstone na svaku zivu kopiju
+kljuca u svim tabelama, pa na nizim nivoima ne ostaje starija ziva verzija*/
+func fillData(files []*os.File) ([][]byte, error) {
 	merge := len(files)
 	newTableData := make([][]byte, 0)
 
@@ -54,31 +69,35 @@ func fillData(files []*os.File) [][]byte {
 	//initial fill entrys
 	entrys := make([]entry, merge)
 	for i := 0; i < merge; i++ {
-		entrys[i] = readEntry(files[i])
+		var err error
+		entrys[i], err = readEntry(files[i])
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	//iter until end
-	for next := true; next; {
+	for {
 		//best entry, written inserted into newTableData
 		//load is index of file of best
 		best, load := findBest(entrys)
+		if load < 0 {
+			break
+		}
 
 		newTableData = append(newTableData, best.value)
-		entrys[load] = readEntry(files[load])
-
-		//check if there are entrys left in any file
-		someLoaded := true
+		//Starije verzije istog kljuca u ostalim tabelama se preskacu
 		for i := 0; i < merge; i++ {
-			if entrys[i].loaded {
-				someLoaded = true
-				break
-			} else {
-				someLoaded = false
+			if entrys[i].loaded && entrys[i].key == best.key {
+				var err error
+				entrys[i], err = readEntry(files[i])
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
-		next = someLoaded
 	}
-	return newTableData
+	return newTableData, nil
 }
 
 func loadTables(merge int, level int) ([]*os.File, bool) {
@@ -100,6 +119,8 @@ func loadTables(merge int, level int) ([]*os.File, bool) {
 }
 
 func tidyLevel(level int, merge int, lastTable int) {
+	//Tabele nivoa se brisu i preimenuju, pa otvorene tabele u kesu vise ne vaze
+	SSTable.InvalidateLevel(level)
 	for i := 1; i <= merge; i++ {
 		name := strconv.Itoa(level) + "_" + strconv.Itoa(i)
 		err := os.RemoveAll("data/SSTable" + name)
@@ -143,72 +164,63 @@ func tidyLevel(level int, merge int, lastTable int) {
 		if err != nil {
 			log.Fatal(err)
 		}
+		//rename prefix filter, ako tabela ima
+		err = os.Rename("data/SSTable"+newName+"/prefix"+name+".txt", "data/SSTable"+newName+"/prefix"+newName+".txt")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Fatal(err)
+		}
 
 	}
 }
 
-func readEntry(file *os.File) entry {
+//Cita sledeci zapis iz fajla i proverava njegov CRC
+//Na kraju fajla vraca entry sa loaded = false, a za ostecen zapis SSTable.ErrCorrupted
+func readEntry(file *os.File) (entry, error) {
 	var ret entry
 	ret.loaded = true
 	ret.value = make([]byte, 0)
-	bytes := make([]byte, 4)
-	_, err := file.Read(bytes)
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return ret, err
+	}
+	bytes := make([]byte, 1)
+	_, err = file.Read(bytes)
 	if err != nil {
 		ret.loaded = false
-		return ret
-	}
-	ret.value = append(ret.value, bytes...)
-	bytes = make([]byte, 8)
-	file.Read(bytes)
-	ret.value = append(ret.value, bytes...)
-	ret.date = binary.LittleEndian.Uint64(bytes)
-	bytes = make([]byte, 1)
-	file.Read(bytes)
-	ret.value = append(ret.value, bytes...)
-	if int(bytes[0]) == 0 {
+		return ret, nil
+	}
+	record, err := SSTable.ReadRecord(file, uint64(offset))
+	if err != nil {
+		return ret, err
+	}
+	_, err = file.Seek(offset+int64(len(record)), io.SeekStart)
+	if err != nil {
+		return ret, err
+	}
+	ret.value = record
+	ret.date = binary.LittleEndian.Uint64(record[4:12])
+	if int(record[12]) == 0 {
 		ret.tombstone = false
 	} else {
 		ret.tombstone = true
 	}
-	bytes = make([]byte, 8)
-	file.Read(bytes)
-	ret.value = append(ret.value, bytes...)
-	keySize := binary.LittleEndian.Uint64(bytes)
-	file.Read(bytes)
-	ret.value = append(ret.value, bytes...)
-	valueSize := binary.LittleEndian.Uint64(bytes)
-	bytes = make([]byte, keySize)
-	file.Read(bytes)
-	ret.value = append(ret.value, bytes...)
-	ret.key = string(bytes)
-	bytes = make([]byte, valueSize)
-	file.Read(bytes)
-	ret.value = append(ret.value, bytes...)
-	return ret
+	keySize := binary.LittleEndian.Uint64(record[13:21])
+	ret.key = string(record[29 : 29+keySize])
+	return ret, nil
 }
 
+//Vraca zapis sa najmanjim kljucem, a za isti kljuc najnoviju verziju
+//Pri istom vremenu je novija tabela sa vecim rednim brojem. Ako nijedan zapis nije ucitan, load je -1
 func findBest(entrys []entry) (entry, int) {
 	var best entry
-	merge := len(entrys)
-	best.date = entrys[0].date
-	best.value = entrys[0].value
-	//from witch table load new entry
-	load := 0
-	for i := 1; i < merge; i++ {
-		if entrys[i].loaded {
-			if best.key < entrys[i].key {
-				if best.key == entrys[i].key {
-					if best.date < entrys[i].date {
-						best.value = entrys[i].value
-						best.date = entrys[i].date
-						load = i
-					}
-				} else {
-					best.value = entrys[i].value
-					best.date = entrys[i].date
-					load = i
-				}
-			}
+	load := -1
+	for i := 0; i < len(entrys); i++ {
+		if !entrys[i].loaded {
+			continue
+		}
+		if load < 0 || entrys[i].key < best.key || (entrys[i].key == best.key && entrys[i].date >= best.date) {
+			best = entrys[i]
+			load = i
 		}
 	}
 	return best, load
@@ -0,0 +1,24 @@
+package kompakcije
+
+import "testing"
+
+//Za isti kljuc pobedjuje najnovija verzija, a pri istom vremenu tabela sa vecim indeksom
+func TestFindBestNewestVersion(t *testing.T) {
+	entrys := []entry{
+		{key: "b", date: 5, value: []byte("b5"), loaded: true},
+		{key: "a", date: 1, value: []byte("a1"), loaded: true},
+		{key: "a", date: 3, value: []byte("a3"), loaded: true},
+		{key: "a", date: 3, value: []byte("a3'"), loaded: true},
+		{key: "0", date: 9, loaded: false},
+	}
+	best, load := findBest(entrys)
+	if load != 3 || string(best.value) != "a3'" {
+		t.Fatalf("ocekivan zapis a3' iz tabele 3, dobijen %q iz tabele %d", best.value, load)
+	}
+}
+
+func TestFindBestNothingLoaded(t *testing.T) {
+	if _, load := findBest(make([]entry, 3)); load != -1 {
+		t.Fatalf("ocekivano -1, dobijeno %d", load)
+	}
+}
@@ -1,112 +1,204 @@
 package main
 
 import (
-	"container/list"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
 )
 
-/*cache_list je dvostruko spregnuta lista u kojoj se nalaze elementi cache-a
-cache_map omogucava O(1) pristup
-cache_limit je max broj stavki u cache-u*/
-type Cache struct{
-	 cache_list *list.List
-	 cache_map map[string]*list.Element
-	 cache_limit int
+/*Cache je podeljen na shard-ove, svaki shard ima svoju bravu i svoju politiku izbacivanja (LRU, LFU, 2Q, ARC, W-TinyLFU)
+Kljuc uvek pripada istom shard-u (fnv hash kljuca), pa se pristupi razlicitim shard-ovima ne blokiraju
+Velicina je ogranicena u bajtovima (kljuc + vrednost), a svaki shard dobija jednak deo*/
+type Cache struct {
+	//Brojaci se menjaju atomicno, pa su prvi u strukturi zbog poravnanja na 32-bitnim platformama
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	shards    []*cacheShard
+	max_bytes int
+}
 
+/*Shard cache-a - politika cuva stavke, a max_bytes je max zbir njihovih velicina*/
+type cacheShard struct {
+	mutex     sync.Mutex
+	policy    CachePolicy
+	max_bytes int
 }
 
 /*Model podatka u dvostruko spregnutoj listi*/
 type KV struct {
-	key string
+	key   string
 	value []byte
 }
 
+/*Statistika cache-a*/
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Items     int
+	Bytes     int
+	MaxBytes  int
+}
+
+func (stats CacheStats) String() string {
+	ratio := 0.0
+	if stats.Hits+stats.Misses != 0 {
+		ratio = float64(stats.Hits) / float64(stats.Hits+stats.Misses) * 100
+	}
+	return fmt.Sprintf("cache: %d hits, %d misses (%.1f%% hit ratio), %d evictions, %d items, %d/%d bytes",
+		stats.Hits, stats.Misses, ratio, stats.Evictions, stats.Items, stats.Bytes, stats.MaxBytes)
+}
+
 /*Kreiranje novog kesa
-limit je procitan podatak iz eksterne konfiguracije koji predstavlja max broj stavki u cache-u*/
-func createCache(limit int) *Cache {
-	return &Cache{cache_list: list.New(),cache_map: make(map[string]*list.Element),cache_limit: limit}
+max_bytes, shards i naziv politike su procitani iz eksterne konfiguracije*/
+func createCache(max_bytes int, shards int, policy string) (*Cache, error) {
+	if shards < 1 {
+		shards = 1
+	}
+	cache := &Cache{shards: make([]*cacheShard, shards), max_bytes: max_bytes}
+	for i := range cache.shards {
+		shard_policy, err := newCachePolicy(policy, max_bytes/shards)
+		if err != nil {
+			return nil, err
+		}
+		cache.shards[i] = &cacheShard{policy: shard_policy, max_bytes: max_bytes / shards}
+	}
+	return cache, nil
+}
+
+/*Pravi novi cache sa drugim kapacitetom, brojem shard-ova ili politikom i prenosi u njega stavke starog
+Stavke se ubacuju od onih koje bi prve bile izbacene ka najvrednijim, pa redosled izbacivanja ostaje priblizno isti
+Brojaci se zadrzavaju, a stari cache se posle poziva vise ne koristi*/
+func (cache *Cache) rebuild(max_bytes int, shards int, policy string) (*Cache, error) {
+	rebuilt, err := createCache(max_bytes, shards, policy)
+	if err != nil {
+		return nil, err
+	}
+	rebuilt.hits = atomic.LoadUint64(&cache.hits)
+	rebuilt.misses = atomic.LoadUint64(&cache.misses)
+	rebuilt.evictions = atomic.LoadUint64(&cache.evictions)
+	for _, shard := range cache.shards {
+		shard.mutex.Lock()
+		keys := shard.policy.Keys()
+		for i := len(keys) - 1; i >= 0; i-- {
+			value, _ := shard.policy.Get(keys[i])
+			rebuilt.shard(keys[i]).policy.Put(keys[i], value)
+		}
+		shard.mutex.Unlock()
+	}
+	return rebuilt, nil
+}
+
+func (cache *Cache) shard(key string) *cacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return cache.shards[h.Sum32()%uint32(len(cache.shards))]
 }
 
 /*Trazenje stavke u cache-u
-prosledjuje se kljuc koji se trazi*/
-func (cache *Cache) Search(key string) *list.Element {
-	value,is_present := cache.cache_map[key]
-
-	if !is_present{
-		return nil
-	} else{
-		cache.cache_list.MoveToBack(value)
-		return value
+prosledjuje se kljuc koji se trazi, stavka se pomera na kraj liste*/
+func (cache *Cache) Search(key string) ([]byte, bool) {
+	shard := cache.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	value, is_present := shard.policy.Get(key)
+	if !is_present {
+		atomic.AddUint64(&cache.misses, 1)
+		return nil, false
 	}
+	atomic.AddUint64(&cache.hits, 1)
+	return value, true
 }
 
 /*Pomocna funkcija za testiranje
-Ispisuje se ceo sadrzaj cache-a
-Prosledjuje se prethodno kreiran cache objekat*/
-func (cache *Cache) printCache(){
-	for e := cache.cache_list.Front(); e != nil; e = e.Next() {
-		print(string(e.Value.(KV).key )+ " ")
-
+Ispisuje se ceo sadrzaj cache-a, shard po shard*/
+func (cache *Cache) printCache() {
+	for _, shard := range cache.shards {
+		shard.mutex.Lock()
+		for _, key := range shard.policy.Keys() {
+			print(key + " ")
+		}
+		shard.mutex.Unlock()
 	}
 	println()
 }
 
-
 /*Dodavanje stavke u cache
-Prosledjuje se kljuc sa njemu pridruzenom vrednoscu*/
-func (cache *Cache) Insert(key string,value []byte){
-	found := cache.Search(key)
-	if found == nil{
-		if cache.cache_list.Len() == cache.cache_limit{
-			lru := cache.cache_list.Front()
-			cache.cache_list.Remove(lru)
-			delete(cache.cache_map,lru.Value.(KV).key)
-		}
+Prosledjuje se kljuc sa njemu pridruzenom vrednoscu, politika shard-a bira sta se izbacuje
+Stavka veca od shard-a se ne ubacuje*/
+func (cache *Cache) Insert(key string, value []byte) {
+	shard := cache.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	evicted := shard.policy.Put(key, value)
+	atomic.AddUint64(&cache.evictions, uint64(evicted))
+}
+
+/*Da li je kljuc u cache-u, bez pomeranja stavke i bez brojanja pogotka ili promasaja*/
+func (cache *Cache) Contains(key string) bool {
+	shard := cache.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	return shard.policy.Contains(key)
+}
 
+/*Kljucevi svih shard-ova od najvrednijih ka onima koji bi prvi bili izbaceni
+Shard-ovi se preplicu (prvi kljuc svakog shard-a, pa drugi...), jer je redosled poznat samo unutar shard-a*/
+func (cache *Cache) Keys() []string {
+	lists := make([][]string, len(cache.shards))
+	total := 0
+	for i, shard := range cache.shards {
+		shard.mutex.Lock()
+		lists[i] = shard.policy.Keys()
+		shard.mutex.Unlock()
+		total += len(lists[i])
+	}
+	keys := make([]string, 0, total)
+	for rank := 0; len(keys) < total; rank++ {
+		for _, list := range lists {
+			if rank < len(list) {
+				keys = append(keys, list[rank])
+			}
+		}
+	}
+	return keys
+}
 
-	} else{
-		//Azurirace se vrednost pod zadatim kljucem - prethodna se brise
-		cache.cache_list.Remove(found)
+/*Da li bi ubacivanje nove stavke izbacilo neku drugu iz cache-a*/
+func (cache *Cache) WouldEvict(key string, value []byte) bool {
+	shard := cache.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if shard.policy.Contains(key) {
+		return false
 	}
-	element := cache.cache_list.PushBack(KV{key:key,value: value})
-	cache.cache_map[key] = element
+	return shard.policy.Bytes()+entrySize(key, value) > shard.max_bytes
 }
 
 //Kada se uputi delete zahtev, ako kljuca ima u cache - u, on se brise
 //prima kljuc koji se brise
-func (cache *Cache) DeleteKey(key string){
-	value := cache.Search(key)
-	if value != nil{
-		cache.cache_list.Remove(value)
-		delete(cache.cache_map,key)
-
-	}else{
-		//println("Kljuc ne postoji u cache-u!")
-	}
+func (cache *Cache) DeleteKey(key string) {
+	shard := cache.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	shard.policy.Remove(key)
 }
 
-
-/*Testiranje - brise se u izvrsnoj verziji*/
-/*func main(){
-	cache := createCache(3)
-	/*println(cache.Search("1").Value.(string))
-	cache.printCache()
-	cache.Insert("1",[]byte("maja"))
-	cache.printCache()
-	cache.Insert("2",[]byte("varga"))
-	cache.printCache()
-	cache.Insert("3",[]byte("overflow"))
-	cache.printCache()
-	println(string(cache.Search("2").Value.(KV).value))
-	cache.printCache()
-	cache.Insert("3",[]byte("another_overflow"))
-	cache.printCache()
-	cache.Insert("4",[]byte("too_much"))
-	cache.printCache()
-	println(cache.Search("1"))
-	println(string(cache.Search("2").Value.(KV).value))
-	cache.printCache()
-	cache.DeleteKey("4")
-	cache.printCache()
-	cache.Search("3")
-	cache.printCache()
-}*/
+func (cache *Cache) Statistics() CacheStats {
+	stats := CacheStats{
+		Hits:      atomic.LoadUint64(&cache.hits),
+		Misses:    atomic.LoadUint64(&cache.misses),
+		Evictions: atomic.LoadUint64(&cache.evictions),
+		MaxBytes:  cache.max_bytes,
+	}
+	for _, shard := range cache.shards {
+		shard.mutex.Lock()
+		stats.Items += shard.policy.Len()
+		stats.Bytes += shard.policy.Bytes()
+		shard.mutex.Unlock()
+	}
+	return stats
+}
@@ -1,122 +1,192 @@
 package main
 
 import (
-	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
 	"fmt"
-	"main/SSTable"
-	"main/kompakcije"
+	"kv/SSTable"
+	"kv/merkle_tree"
+	"kv/simhash"
+	"kv/topk"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 //Strukture koje se nalaze u memoriji i konfiguracioni objekat sa iscitanom konfiguracijom
 type System struct {
-	memtable *Memtable
-	cache    *Cache
-	config   *ConfigObj
+	memtable  *Memtable
+	cache     *Cache
+	negative  *NegativeCache //potvrdjeni promasaji, nil ako je iskljucen
+	config    *ConfigObj
+	documents *simhash.Index       //otisci dokumenata, gradi se pri prvoj upotrebi
+	expires   map[string]time.Time //vremena isteka kljuceva, gradi se pri prvoj upotrebi
+	expired   int64                //broj kljuceva obrisanih po isteku vremena
+	reads     *topk.Window         //najcitaniji kljucevi u kliznom prozoru
+	writes    *topk.Window         //najcesce upisivani kljucevi u kliznom prozoru
+
+	//Operacije nad sistemom nisu bezbedne za istovremeno izvrsavanje, pa svaki pozivalac iz druge gorutine
+	//(ponovno ucitavanje konfiguracije, servisi) drzi bravu dok traje operacija
+	mutex sync.Mutex
 }
 
 var (
 	system *System
 )
 
-//Objekat koji ima sva podesavanja za projekat
-type ConfigObj struct {
+/*Kljucevi koji pocinju nultim bajtom su rezervisani za interne zapise sistema (baketi tokena, otisci dokumenata, vremena isteka)
+Skeniranja ih ne vracaju, a konzola, REST i RESP server odbijaju takve kljuceve, pa ih korisnik ne moze procitati ni prepisati*/
+const reservedPrefix = "\x00"
 
-	//WAL
-	batch_size   int
-	segment_size int
-	low_w_mark   int
-
-	//Token Bucket
-	tokens  int
-	minutes float64
-
-	//LRU Cache
-	cache_limit int
-
-	//Memtable
-	mem_max_size int
-	threshold    float64 //procenentualno broj nakon kojeg se Flush-uje
-
-	//Bloom filter
-	bloom_precision float64
+func isReservedKey(key string) bool {
+	return strings.HasPrefix(key, reservedPrefix)
+}
 
-	//LSM stabla i kompakcije
-	max_height      int //max visina lsm stabla (BEZ Memtabele)
-	compaction_size int //broj tabela koje se spajaju
+//Greska za korisnicki kljuc iz rezervisanog dela prostora kljuceva, nil za ispravan kljuc
+func checkUserKey(key string) error {
+	if isReservedKey(key) {
+		return fmt.Errorf("kljuc %q je rezervisan (pocinje nultim bajtom)", key)
+	}
+	return nil
 }
 
+/*Cache i upisi: cache sadrzi samo vrednosti procitane kroz get i nikad nije noviji od memtabele i SSTabela
+put i Delete izbacuju kljuc iz cache-a (invalidate-on-write) posle upisa, pa sledeci get cita novu vrednost
+i ponovo je ubacuje. Upis se ne prepisuje u cache (write-through), jer bi kljucevi koji se samo pisu izbacivali citane
+Flush i kompakcija ne menjaju vrednosti kljuceva, pa ne diraju cache. Svaki upis (i svaki zapis grupe upisa
+koji WAL baferuje) prolazi kroz write (koji poziva i put) ili Delete, a isto vazi i za svako buduce brisanje po isteku vremena*/
 func (sys *System) put(user string, key string, value []byte) bool {
 	if user != "" {
 		if !CheckTokenBucket(user) { // korisnik nema vise tokena
 			return false
 		}
 	}
-	err, wal_in := log.WritePutBuffer(key, value)
-	if err != nil {
-		fmt.Println(err)
+	sys.writes.Add(key)
+	if !sys.write(key, value) {
 		return false
 	}
+	sys.dropExpire(key)
+	return true
+}
 
-	var full_percent float64
-	full_percent = 0
-	if sys.memtable.curr_size != 0 {
-		full_percent = (float64(sys.memtable.curr_size) / float64(sys.memtable.max_size)) * 100
-	}
+//Upis koji se ne broji medju najcesce upisivanim kljucevima - za interne zapise sistema
+//(baketi tokena, vrednosti sa tipom, ponovno izvrsavanje WAL-a), inace bi potisnuli korisnicke kljuceve
+func (sys *System) write(key string, value []byte) bool {
+	sys.negative.Invalidate(key)
 
-	if full_percent >= sys.memtable.threshold {
+	//Flush pravi novi WAL, pa se zapis upisuje u WAL tek posle njega - inace bi se izgubio pri ponovnom pokretanju
+	if sys.memtable.Full() || log.OverWaterMark() {
 		println("puno")
-		data, err := sys.memtable.Flush()
-		if err == nil {
-			SSTable.MakeTable(data, 1, sys.config.bloom_precision)
-		} else {
+		err := sys.Flush()
+		if err != nil {
 			fmt.Println(err)
 			return false
 		}
 	}
 
+	err, wal_in := log.WritePutBuffer(key, value)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
 	_, err = sys.memtable.PutElement(wal_in)
 	if err != nil {
 		fmt.Println(err)
 		return false
 	}
+	sys.cache.DeleteKey(key)
+	sys.dropDocument(key)
 
 	return true
 
 }
 
+//Upisuje memtabelu u novu SSTabelu prvog nivoa i prazni je
+func (sys *System) Flush() error {
+	data, err := sys.memtable.Flush()
+	if err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		SSTable.MakeTable(data, 1, sys.config.bloom_precision)
+	}
+	return nil
+}
+
 func (sys *System) get(user string, key string) []byte {
 	if user != "" {
 		if !CheckTokenBucket(user) { // korisnik nema vise tokena
 			return nil
 		}
 	}
+	if sys.expire(key) {
+		return nil
+	}
+	sys.reads.Add(key)
+	return sys.read(key)
+}
+
+//Citanje koje se ne broji medju najcitanijim kljucevima, za interne zapise kao i write
+func (sys *System) read(key string) []byte {
 	//Ako nije pronadjeno u kesu i mem tabili
 	mem_val := sys.memtable.GetElement(key)
 	if mem_val == nil {
-		cache_val := sys.cache.Search(key) //ukoliko je podatak u cache-u,on ga automatski propagira na prvo mesto
-		if cache_val == nil {
-			value, found := SSTable.Find(key, sys.config.max_height)
-			if found {
+		cache_val, cached := sys.cache.Search(key) //ukoliko je podatak u cache-u,on ga automatski propagira na prvo mesto
+		if !cached {
+			value := sys.readTables(key)
+			if value != nil {
 				//print("iz ss-a je.")
-				sys.cache.Insert(key, value)
-				return value
-			} else {
-				return nil
+				sys.cacheInsert(key, value)
 			}
+			return value
 		} else {
 			//print("iz kesa je.")
-			return cache_val.Value.(KV).value
+			return cache_val
 		}
 	} else {
 		//print("iz mema je.")
-		sys.cache.Insert(key, mem_val)
+		sys.cacheInsert(key, mem_val)
 		return mem_val
 	}
-	return nil
+}
 
+//Cita kljuc kog nema u memtabeli ni u cache-u iz SSTabela, potvrdjen promasaj se pamti u negativnom cache-u
+func (sys *System) readTables(key string) []byte {
+	if sys.negative.Contains(key) {
+		return nil
+	}
+	value, found, err := SSTable.Find(key, sys.config.max_height)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	if !found {
+		sys.negative.Add(key)
+		return nil
+	}
+	return value
+}
+
+//Broj delova kliznog prozora vrucih kljuceva - prozor klizi u koracima od prozor/hotKeySlices
+const hotKeySlices = 6
+
+//Ubacuje vrednost u cache
+//Ako je ukljucena prednost vrucih kljuceva, u pun cache ulaze samo kljucevi procitani bar dva puta u prozoru
+func (sys *System) cacheInsert(key string, value []byte) {
+	if sys.config.hot_keys_admission && sys.cache.WouldEvict(key, value) && sys.reads.Count(key) < 2 {
+		return
+	}
+	sys.cache.Insert(key, value)
+}
+
+//Vraca n najcitanijih i n najcesce upisivanih kljuceva u kliznom prozoru
+func (sys *System) HotKeys(n int) ([]topk.Counter, []topk.Counter) {
+	return sys.reads.Top(n), sys.writes.Top(n)
 }
 
 func (sys *System) Delete(user string, key string) bool {
@@ -125,267 +195,465 @@ func (sys *System) Delete(user string, key string) bool {
 			return false
 		}
 	}
+	if log.OverWaterMark() {
+		err := sys.Flush()
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+	}
 	err := log.WriteDeleteBuffer(key)
 	if err != nil {
 		fmt.Println(err)
 		return false
 	}
 	sys.cache.DeleteKey(key)
-	if sys.memtable.DeleteElement(key) == false {
-		if SSTable.Delete(key, sys.config.max_height) {
-			return true
-		} else {
+	deleted := sys.memtable.DeleteElement(key)
+	//Starije verzije kljuca u SSTabelama se takodje brisu, inace bi get ili flush (koji izostavlja
+	//obrisane zapise iz memtabele) ponovo otkrili staru vrednost. SSTable.Delete brise najnoviju zivu verziju
+	for {
+		found, err := SSTable.Delete(key, sys.config.max_height)
+		if err != nil {
+			fmt.Println(err)
 			return false
 		}
+		if !found {
+			break
+		}
+		deleted = true
 	}
-
-	return true
+	sys.dropDocument(key)
+	sys.dropExpire(key)
+	return deleted
 }
 
-//Incijalizacija memtabele, cache-a i konfiguracionog objekta
-func CreateSystem() *System {
-	config_obj := Default()
-	config_obj.ReadConfig("config.txt")
-	return &System{
-		memtable: NewMemtable(config_obj.mem_max_size, config_obj.threshold),
-		cache:    createCache(config_obj.cache_limit),
-		config:   config_obj,
+//Vraca sve kljuceve sa zadatim prefiksom i njihove vrednosti, sortirane po kljucu
+//Memtabela je novija od SSTabela, a obrisani kljucevi se ne vracaju
+func (sys *System) PrefixScan(user string, prefix string) []KV {
+	if user != "" {
+		if !CheckTokenBucket(user) { // korisnik nema vise tokena
+			return nil
+		}
+	}
+	records, _, err := SSTable.PrefixScan(prefix, sys.config.max_height)
+	if err != nil {
+		fmt.Println(err)
+		return nil
 	}
+	return sys.mergeScan(records, func(key string) bool {
+		return strings.HasPrefix(key, prefix) && !isReservedKey(key) && !sys.isExpired(key)
+	}, 0)
 }
 
-//Kreira objekat sa podrazumevanim vrednostima
-func Default() *ConfigObj {
-	return &ConfigObj{
-		batch_size:   3,
-		segment_size: 6,
-		low_w_mark:   3,
-
-		tokens:  50,
-		minutes: 1,
-
-		cache_limit: 3,
-
-		mem_max_size: 5,
-		threshold:    80,
-
-		bloom_precision: 0.1,
-
-		max_height:      3,
-		compaction_size: 2,
+//Kao PrefixScan, ali za interne zapise iz rezervisanog dela prostora kljuceva i bez trosenja tokena
+func (sys *System) reservedScan(prefix string) []KV {
+	records, _, err := SSTable.PrefixScan(prefix, sys.config.max_height)
+	if err != nil {
+		fmt.Println(err)
+		return nil
 	}
+	return sys.mergeScan(records, func(key string) bool { return strings.HasPrefix(key, prefix) }, 0)
 }
 
-//Funkcija proverava ispravnost vrednosti u eksternoj konfiguraciji za CELE BROJEVE
-//min i max su opsezi u kojima se vrednost moze naci
-//Vraca indikator - true = ispravno, false = neispravno i konvertovanu vrednost ukoliko je tacno, -1 ukoliko je netacno
-func CheckValInt(val string, min int, max int) (bool, int) {
-	value, err := strconv.Atoi(val)
-	if err != nil { //podatak nije celobrojnog tipa
-		return false, -1
-	} else {
-		if value < min || value > max { //van opsega
-			return false, -1
-		} else {
-			return true, value //sve je uredu
+//Vraca kljuceve iz opsega [start, end) i njihove vrednosti, sortirane po kljucu, najvise limit (0 - bez ogranicenja)
+//Prazan end znaci opseg bez gornje granice. Sa limit > 0 SSTabele se citaju u delovima dok se ne skupi limit kljuceva
+func (sys *System) RangeScan(user string, start string, end string, limit int) []KV {
+	if user != "" {
+		if !CheckTokenBucket(user) { // korisnik nema vise tokena
+			return nil
 		}
 	}
-
+	result := make([]KV, 0)
+	for {
+		records, next, err := SSTable.RangeScan(start, end, sys.config.max_height, limit)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+		from := start
+		kvs := sys.mergeScan(records, func(key string) bool {
+			return key >= from && (end == "" || key < end) && (next == "" || key < next) && !isReservedKey(key) && !sys.isExpired(key)
+		}, limit-len(result))
+		result = append(result, kvs...)
+		if next == "" || len(result) == limit {
+			return result
+		}
+		start = next
+	}
 }
 
-//Funkcija proverava ispravnost vrednosti u eksternoj konfiguraciji za REALNE BROJEVE
-//min i max su opsezi u kojima se vrednost moze naci
-//Vraca indikator - true = ispravno, false = neispravno i konvertovanu vrednost ukoliko je tacno, -1 ukoliko je netacno
-func CheckValFloat(val string, min float64, max float64) (bool, float64) {
-	value, err := strconv.ParseFloat(val, 64)
-	if err != nil { //podatak nije realnog tipa
-		return false, -1
-	} else {
-		if value < min || value > max { //van opsega
-			return false, -1
-		} else {
-			return true, value //sve je uredu
+//Spaja zapise iz SSTabela sa zapisima memtabele za koje match vazi i izbacuje obrisane
+func (sys *System) mergeScan(records [][]byte, match func(key string) bool, limit int) []KV {
+	//Kasniji cvor skip liste sa istim kljucem je noviji (kljuc ponovo upisan nakon brisanja)
+	newest := make(map[string][]byte)
+	for _, record := range sys.memtable.structure.GetAll() {
+		key_size := binary.LittleEndian.Uint64(record[13:21])
+		if match(string(record[29 : 29+key_size])) {
+			newest[string(record[29:29+key_size])] = record
+		}
+	}
+	for _, record := range records {
+		key_size := binary.LittleEndian.Uint64(record[13:21])
+		if !match(string(record[29 : 29+key_size])) {
+			continue
+		}
+		if _, found := newest[string(record[29:29+key_size])]; !found {
+			newest[string(record[29:29+key_size])] = record
 		}
 	}
 
+	result := make([]KV, 0, len(newest))
+	for key, record := range newest {
+		if int(record[12]) == 1 { //tombstone
+			continue
+		}
+		key_size := binary.LittleEndian.Uint64(record[13:21])
+		value_size := binary.LittleEndian.Uint64(record[21:29])
+		result = append(result, KV{key: key, value: record[29+key_size : 29+key_size+value_size]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key < result[j].key })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
 }
 
-//Funkcija iscitava eksterni konfiguracioni fajl na osnovu prosledjene putanje i proverava valjanost vrednosti
-//Menja konfiguracioni objekat (atribute) ukoliko je ispravna vrednost
+//Incijalizacija memtabele, cache-a i konfiguracionog objekta
+func CreateSystem() *System {
+	return newSystem(readConfig())
+}
 
-func (config *ConfigObj) ReadConfig(path string) {
-	file, err := os.Open(path)
+func newSystem(config_obj *ConfigObj) *System {
+	cache, err := createCache(config_obj.cache_limit, config_obj.cache_shards, config_obj.cache_policy)
 	if err != nil {
-		panic(err)
-	}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		pair := strings.Split(line, "=")
-		switch pair[0] {
-		case "batchSize":
-			correct, val := CheckValInt(pair[1], 1, 15)
-			if correct {
-				config.batch_size = val
-			} else {
-				println("batch size neispravan. Koristi se default.")
-			}
-
-		case "segmentSize":
-			correct, val := CheckValInt(pair[1], 2, 10)
-			if correct {
-				config.segment_size = val
-			} else {
-				println("segment size neispravan. Koristi se default.")
-			}
-
-		case "lowWaterMark":
-			correct, val := CheckValInt(pair[1], 1, 10)
-			if correct {
-				config.low_w_mark = val
-			} else {
-				println("low water mark neispravan. Koristi se default.")
-			}
-
-		case "tokens":
-			correct, val := CheckValInt(pair[1], 1, 10000)
-			if correct {
-				config.tokens = val
-			} else {
-				println("tokens neispravan. Koristi se default.")
-			}
-
-		case "minutes":
-			correct, val := CheckValFloat(pair[1], 1, 10)
-			if correct {
-				config.minutes = val
-			} else {
-				println("minutes neispravan. Koristi se default.")
-			}
-
-		case "memMaxSize":
-			correct, val := CheckValInt(pair[1], 1, 100000)
-			if correct {
-				config.mem_max_size = val
-			} else {
-				println("mem max size size neispravan. Koristi se default.")
-			}
-
-		case "memThreshold":
-			correct, val := CheckValFloat(pair[1], 0.1, 100)
-			if correct {
-				config.threshold = val
-			} else {
-				println("memtable threshold neispravan. Koristi se default.")
-			}
+		fmt.Println(err)
+		cache, _ = createCache(config_obj.cache_limit, config_obj.cache_shards, "lru")
+	}
+	return &System{
+		memtable: NewMemtable(config_obj.mem_max_size, config_obj.threshold),
+		cache:    cache,
+		negative: createNegativeCache(config_obj.negative_cache_size, time.Duration(config_obj.negative_cache_ttl)*time.Second),
+		config:   config_obj,
+		reads:    topk.NewWindow(config_obj.hot_keys_capacity, time.Duration(config_obj.hot_keys_window)*time.Second, hotKeySlices),
+		writes:   topk.NewWindow(config_obj.hot_keys_capacity, time.Duration(config_obj.hot_keys_window)*time.Second, hotKeySlices),
+	}
+}
 
-		case "bloomPrecision":
-			correct, val := CheckValFloat(pair[1], 0.000001, 0.9)
-			if correct {
-				config.bloom_precision = val
-			} else {
-				println("bloom precision neispravan. Koristi se default.")
-			}
-		case "maxHeightLSM":
-			correct, val := CheckValInt(pair[1], 1, 10)
-			if correct {
-				config.max_height = val
-			} else {
-				println("LSM max height neispravan. Koristi se default.")
-			}
+//Komanda config - ispisuje vazecu konfiguraciju i izvor svake vrednosti, pa sve greske ako ih ima
+//Vraca izlazni kod procesa: 0 = konfiguracija je ispravna, 2 = neko podesavanje nije ispravno
+func printConfig() int {
+	path := configPath()
+	config, err := LoadConfig(path)
+	if path == "" {
+		path = "nema (samo podrazumevane vrednosti i okruzenje)"
+	}
+	fmt.Println("konfiguracioni fajl: " + path)
+	config.PrintConfig()
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	return 0
+}
 
-		case "compactionSize":
-			correct, val := CheckValInt(pair[1], 2, 10)
-			if correct {
-				config.compaction_size = val
-			} else {
-				println("compaction size neispravan. Koristi se default.")
+//Komanda verify [--rebuild] - ponovo gradi merkle stablo svake SSTabele i poredi ga sa sacuvanim metadata fajlom
+//Stara metadata (bez zaglavlja) pokrivaju samo vrednosti - proveravaju se tako, a --rebuild ih zamenjuje novim
+//Vraca izlazni kod procesa: 0 = sve tabele su ispravne, 1 = pronadjene su razlike, 2 = greska pri proveri,
+//3 = nema razlika, ali neke tabele imaju stara metadata
+func verify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	rebuild := flags.Bool("rebuild", false, "ponovo izgradi stara metadata cije se vrednosti poklapaju")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Println("upotreba: verify [--rebuild]")
+		return 2
+	}
+	config := readConfig()
+	mismatches, legacy, checked, err := SSTable.VerifyAll(config.max_height)
+	for _, m := range mismatches {
+		fmt.Println(m)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	remaining := 0
+	for _, name := range legacy {
+		if *rebuild {
+			if err := SSTable.RebuildLegacyMetadata(name); err != nil {
+				fmt.Println(err)
+				return 2
 			}
-		default:
-			println("Parametar ne postoji!")
+			fmt.Println("SSTable" + name + ": metadata ponovo izgradjena")
+			continue
 		}
+		fmt.Println("SSTable" + name + ": " + merkle_tree.ErrLegacyMetadata.Error() + " (verify --rebuild)")
+		remaining++
+	}
+	fmt.Printf("Provereno tabela: %d, neispravnih zapisa: %d, tabela sa starim metadata: %d\n", checked, len(mismatches), remaining)
+	if len(mismatches) != 0 {
+		return 1
+	}
+	if remaining != 0 {
+		return 3
+	}
+	return 0
+}
 
+//Komanda prove <tabela> <kljuc> - ispisuje hash korena tabele, zapis koji list pokriva i dokaz pripadnosti (hex)
+func prove(args []string) int {
+	if len(args) != 2 {
+		fmt.Println("upotreba: prove <tabela, npr. 1_1> <kljuc>")
+		return 2
 	}
+	proof, root, record, err := SSTable.Prove(args[1], args[0])
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Println("root:      " + hex.EncodeToString(root))
+	fmt.Println("key:       " + record.Key)
+	fmt.Println("value:     " + hex.EncodeToString([]byte(record.Value)))
+	fmt.Println("timestamp: " + strconv.FormatUint(record.Timestamp, 10))
+	fmt.Println("proof:     " + hex.EncodeToString(proof.Marshal()))
+	return 0
+}
 
-	if err := scanner.Err(); err != nil {
-		panic(err)
+//Komanda checkproof <root> <kljuc> <value> <timestamp> <proof> - samostalna provera dokaza
+//root, value i proof su hex. Ne cita nista iz baze, pa je klijent moze pokrenuti bez poverenja u server
+func checkProof(args []string) int {
+	if len(args) != 5 {
+		fmt.Println("upotreba: checkproof <root> <kljuc> <value> <timestamp> <proof>")
+		return 2
 	}
+	root, err := hex.DecodeString(args[0])
+	if err != nil {
+		fmt.Println("neispravan hash korena")
+		return 2
+	}
+	value, err := hex.DecodeString(args[2])
+	if err != nil {
+		fmt.Println("neispravna vrednost")
+		return 2
+	}
+	timestamp, err := strconv.ParseUint(args[3], 10, 64)
+	if err != nil {
+		fmt.Println("neispravan timestamp")
+		return 2
+	}
+	proofBytes, err := hex.DecodeString(args[4])
+	if err != nil {
+		fmt.Println("neispravan dokaz")
+		return 2
+	}
+	proof, err := merkle_tree.UnmarshalProof(proofBytes)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	//Procitana vrednost je ziva, pa je tombstone 0
+	leaf := merkle_tree.Data{Key: args[1], Value: string(value), Timestamp: timestamp}
+	if !merkle_tree.VerifyProof(merkle_tree.HashLeaf(leaf, proof.Algorithm), proof, root) {
+		fmt.Println("dokaz NIJE ispravan")
+		return 1
+	}
+	fmt.Println("dokaz je ispravan")
+	return 0
 }
 
-func (config *ConfigObj) PrintConfig() {
-	println("Batch size:" + strconv.Itoa(config.batch_size))
-	println("Segment size:" + strconv.Itoa(config.segment_size))
-	println("Low water mark:" + strconv.Itoa(config.low_w_mark))
-	println("Tokens:" + strconv.Itoa(config.tokens))
-	println("Minutes:", config.minutes)
-	println("Memtable max size:" + strconv.Itoa(config.mem_max_size))
-	println("Memtable threshold:", config.threshold)
-	println("Bloom filter precision:", config.bloom_precision)
-	println("LSM tree max height:" + strconv.Itoa(config.max_height))
-	println("Compaction size:" + strconv.Itoa(config.compaction_size))
+//Komanda diff <baza> <baza> - ispisuje kljuceve ciji se sadrzaj razlikuje izmedju dve kopije baze
+//Baza je data direktorijum baze koja ne radi, ili unix:<putanja> socket-a instance pokrenute sa --diff-socket
+func diff(args []string) int {
+	if len(args) != 2 {
+		fmt.Println("upotreba: diff <data direktorijum|unix:socket> <data direktorijum|unix:socket>")
+		return 2
+	}
+	var diffs []SSTable.KeyDiff
+	var err error
+	if !strings.HasPrefix(args[0], "unix:") && !strings.HasPrefix(args[1], "unix:") {
+		diffs, err = SSTable.DiffStores(args[0], args[1])
+	} else {
+		diffs, err = diffSources(args[0], args[1])
+	}
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	if len(diffs) != 0 {
+		return 1
+	}
+	return 0
+}
 
+//Ispisuje n najcitanijih i najcesce upisivanih kljuceva u kliznom prozoru
+func printHotKeys(n int) {
+	reads, writes := system.HotKeys(n)
+	fmt.Printf("Najcitaniji kljucevi (poslednjih %ds):\n", system.config.hot_keys_window)
+	for _, counter := range reads {
+		fmt.Printf("  %s\t%d (+-%d)\n", counter.Key, counter.Count, counter.Error)
+	}
+	fmt.Printf("Najcesce upisivani kljucevi (poslednjih %ds):\n", system.config.hot_keys_window)
+	for _, counter := range writes {
+		fmt.Printf("  %s\t%d (+-%d)\n", counter.Key, counter.Count, counter.Error)
+	}
 }
 
-func main() {
-	err := InitWAL()
+//Komanda scan <prefiks> - ispisuje sve kljuceve sa prefiksom iz SSTabela
+func scan(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("upotreba: scan <prefiks>")
+		return 2
+	}
+	config := readConfig()
+	SSTable.InitializePrefixConfigs(config.prefix_extractor)
+	records, skipped, err := SSTable.PrefixScan(args[0], config.max_height)
 	if err != nil {
 		fmt.Println(err)
-		return
+		return 1
 	}
+	for _, record := range records {
+		key_size := binary.LittleEndian.Uint64(record[13:21])
+		value_size := binary.LittleEndian.Uint64(record[21:29])
+		if int(record[12]) == 0 && !isReservedKey(string(record[29:29+key_size])) {
+			fmt.Printf("%s=%s\n", record[29:29+key_size], record[29+key_size:29+key_size+value_size])
+		}
+	}
+	fmt.Printf("Preskoceno tabela: %d\n", skipped)
+	return 0
+}
 
+//Otvara WAL i pravi sistem sa ucitanom konfiguracijom
+func openSystem() bool {
+	/*Zapisi koji pre gasenja ili pada nisu stigli do SSTabela. WAL prethodnog pokretanja se premesta u replayDir
+	i brise tek kada su svi njegovi zapisi upisani u SSTabele, pa pad tokom pokretanja ne gubi nista. Ako replayDir
+	vec postoji, prethodno pokretanje je palo tokom ponovnog izvrsavanja, a wal/ je od tada prazan*/
+	if _, err := os.Stat(replayDir); os.IsNotExist(err) {
+		err = os.Rename("wal", replayDir)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Println(err)
+			return false
+		}
+	}
+	entries, err := ReplayWAL(replayDir)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("WAL nije ponovo izvrsen, segmenti su sacuvani u " + replayDir)
+		return false
+	}
+	err = InitWAL()
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
 	system = CreateSystem()
-	InitializeTokenBucketConfigs(uint32(system.config.tokens), uint32(system.config.minutes))
-	InitializeWALConfigs(system.config.batch_size, system.config.segment_size, system.config.low_w_mark)
-
-	println(system.put("test", "2", []byte("izmena")))
-	println(system.put("test", "1", []byte("prvi testt")))
-	println(system.put("test", "3", []byte("treci testt")))
-	println(system.put("test", "4", []byte("cetvrti testt")))
-
-	println(system.put("test", "22", []byte("izmena")))
-	println(system.put("test", "11", []byte("prvi testt")))
-	println(system.put("test", "33", []byte("treci testt")))
-	println(system.put("test", "44", []byte("cetvrti testt")))
-
-	println(system.put("test", "222", []byte("izmena")))
-	println(system.put("test", "111", []byte("prvi testt")))
-	println(system.put("test", "333", []byte("treci testt")))
-	println(system.put("test", "444", []byte("cetvrti testt")))
-	//println(system.Delete("test", "2"))
-	//println(string(system.get("test", "2")))
-	kompakcije.Kompakcija(system.config.compaction_size, system.config.max_height, system.config.bloom_precision)
-	//println(string(system.get("test", "2")))
-	//println(string(system.get("test", "2")))
-	//println(system.put("test", "", []byte("cetvrti test")))
-	/*println(string(system.get("test", "2")))
-	println(system.put("test", "67", []byte("prvi testt")))
-	println(string(system.get("test", "67")))
-	println(system.put("test", "67", []byte("izmena")))
-	println(string(system.get("test", "67")))*/
-	/*system.cache.printCache()
-	println(string(system.get("test", "2")))
-	/*println(system.put("test", "5", []byte("peti test")))
-	println(string(system.get("test", "5")))
-	println(system.Delete("test", "5"))
-	println(string(system.get("test", "5")))
-	/*system.Delete("test", "2")
-	system.put("test", "2", []byte("drugi test"))
-	system.put("test", "1", []byte("prvi test"))
-	system.put("test", "2", []byte("drugi test"))
-	system.put("test", "1", []byte("prvi test"))
-	system.put("test", "2", []byte("drugi test"))
-	system.put("test", "1", []byte("prvi test"))
-	system.put("test", "2", []byte("drugi test"))
-	system.put("test", "1", []byte("prvi test"))
-	system.put("test", "2", []byte("drugi test"))
-	fmt.Println(system.get("test", "2"))
-	system.Delete("test", "2")
-	system.put("test3", "2", []byte("drugi test"))
-	fmt.Println(system.get("test3", "2"))
-	system.Delete("test3", "2")*/
+	system.initializeConfigs(true)
+	err = system.replay(entries)
+	if err == nil {
+		err = os.RemoveAll(replayDir)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	system.watchConfig()
+	system.startCacheWarmup()
+	return true
+}
 
+//Direktorijum u koji se premesta WAL prethodnog pokretanja dok se ne izvrsi ponovo
+const replayDir = "wal.replay"
+
+/*Ponovo izvrsava upise i brisanja iz WAL-a prethodnog pokretanja, redom kojim su se desili
+Zapisi idu direktno u memtabelu sa svojim vremenom, bez upisa u novi WAL, a na kraju se memtabela upisuje
+u SSTabelu - tek tada se stari segmenti smeju obrisati. Ponovno izvrsavanje istih zapisa ne menja rezultat*/
+func (sys *System) replay(entries []EntryWAL) error {
+	for _, entry := range entries {
+		if entry.tombstone == 1 {
+			sys.memtable.DeleteElement(entry.key)
+			for {
+				found, err := SSTable.Delete(entry.key, sys.config.max_height)
+				if err != nil {
+					return err
+				}
+				if !found {
+					break
+				}
+			}
+			continue
+		}
+		if sys.memtable.Full() {
+			err := sys.Flush()
+			if err != nil {
+				return err
+			}
+		}
+		sys.memtable.PutElement(formBytesPutAt(entry.key, entry.value, entry.timestamp))
+	}
+	//Otisci dokumenata iz WAL-a su upisani mimo indeksa, pa se on gradi iznova pri prvoj upotrebi
+	sys.documents = nil
+	if len(entries) == 0 {
+		return nil
+	}
+	return sys.Flush()
+}
+
+//Uredno gasenje - cuva kljuceve iz cache-a i upisuje preostale zapise WAL-a
+//Brava sistema ostaje zauzeta, pa posle gasenja nijedna operacija ne moze da pocne
+func closeSystem() int {
+	system.mutex.Lock()
+	//Kljucevi iz cache-a se cuvaju samo pri urednom gasenju
+	err := system.cache.SaveKeys(cacheKeysPath)
+	if err != nil {
+		fmt.Println(err)
+	}
 	err = log.Close()
 	if err != nil {
 		fmt.Println(err)
-		return
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			os.Exit(verify(os.Args[2:]))
+		case "prove":
+			os.Exit(prove(os.Args[2:]))
+		case "checkproof":
+			os.Exit(checkProof(os.Args[2:]))
+		case "diff":
+			os.Exit(diff(os.Args[2:]))
+		case "scan":
+			os.Exit(scan(os.Args[2:]))
+		case "config":
+			os.Exit(printConfig())
+		case "serve":
+			os.Exit(serveHTTP(os.Args[2:]))
+		case "resp":
+			os.Exit(serveRESP(os.Args[2:]))
+		case "redis":
+			os.Exit(redisClient(os.Args[2:]))
+		}
+	}
+	//Bez argumenata, sa opcijama ili komandom shell pokrece se konzola
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "shell" {
+		args = args[1:]
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		fmt.Println("nepoznata komanda " + args[0])
+		os.Exit(2)
+	}
+
+	if !openSystem() {
+		os.Exit(1)
+	}
+	status := runShell(system, args)
+	if closeSystem() != 0 {
+		os.Exit(1)
 	}
+	os.Exit(status)
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"kv/SSTable"
+	"kv/kompakcije"
+	"kv/merkle_tree"
+	"os"
+	"strconv"
+	"testing"
+)
+
+//Zapisi iz WAL-a prethodnog pokretanja zavrsavaju u SSTabelama sa svojim vremenom, a stari segmenti se brisu tek posle toga
+func TestOpenSystemReplaysWAL(t *testing.T) {
+	chdirTemp(t)
+	if err := InitWAL(); err != nil {
+		t.Fatal(err)
+	}
+	log.WritePutBuffer("a", []byte("1"))
+	log.WritePutBuffer("b", []byte("2"))
+	log.WriteDeleteBuffer("b")
+	log.WritePutBuffer("c", []byte("3"))
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReplayWAL("wal")
+	if err != nil || len(entries) != 4 {
+		t.Fatalf("ocekivana 4 zapisa, dobijeno %d, %v", len(entries), err)
+	}
+
+	if !openSystem() {
+		t.Fatal("pokretanje nije uspelo")
+	}
+	t.Cleanup(func() { log.Close() })
+	if _, err := os.Stat(replayDir); !os.IsNotExist(err) {
+		t.Fatalf("%s nije obrisan posle ponovnog izvrsavanja", replayDir)
+	}
+	if system.memtable.curr_size != 0 {
+		t.Fatal("ponovo izvrseni zapisi nisu upisani u SSTabelu")
+	}
+	for key, expected := range map[string]string{"a": "1", "b": "", "c": "3"} {
+		if value := string(system.get("", key)); value != expected {
+			t.Errorf("get(%s) = %q, ocekivano %q", key, value, expected)
+		}
+	}
+
+	records, _, err := SSTable.RangeScan("", "", system.config.max_height, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		key := string(record[29 : 29+binary.LittleEndian.Uint64(record[13:21])])
+		timestamp := binary.LittleEndian.Uint64(record[4:12])
+		if key == "a" && timestamp != entries[0].timestamp || key == "c" && timestamp != entries[3].timestamp {
+			t.Errorf("%s: vreme %d nije vreme iz WAL-a", key, timestamp)
+		}
+	}
+}
+
+//Ostecen zapis prekida pokretanje, a segmenti ostaju za rucni pregled
+func TestOpenSystemKeepsWALOnCorruption(t *testing.T) {
+	chdirTemp(t)
+	if err := InitWAL(); err != nil {
+		t.Fatal(err)
+	}
+	log.WritePutBuffer("a", []byte("1"))
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile("wal/wal_0", os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte{1, 2, 3})
+	file.Close()
+
+	if openSystem() {
+		log.Close()
+		t.Fatal("pokretanje sa ostecenim WAL-om je uspelo")
+	}
+	if _, err := os.Stat(replayDir + "/wal_0"); err != nil {
+		t.Fatalf("segment nije sacuvan: %v", err)
+	}
+}
+
+//Kompakcija spusta tabele do nivoa max_height, pa citanje, skeniranja i brisanje moraju obici i taj nivo
+func TestReadsBottomLevel(t *testing.T) {
+	sys := newTestSystem(t)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		sys.put("", key, []byte(key+"1"))
+		sys.Flush()
+	}
+	err := kompakcije.Kompakcija(sys.config.compaction_size, sys.config.max_height, sys.config.bloom_precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bottom := strconv.Itoa(sys.config.max_height) + "_1"
+	if _, err := os.Stat("data/SSTable" + bottom); err != nil {
+		t.Fatalf("tabela %s ne postoji: %v", bottom, err)
+	}
+	if SSTable.FindLastFile(1) != 1 || SSTable.FindLastFile(2) != 1 {
+		t.Fatal("kompakcija je ostavila tabele na visim nivoima")
+	}
+
+	if value := string(sys.get("", "c")); value != "c1" {
+		t.Fatalf("get(c) = %q", value)
+	}
+	if kvs := sys.RangeScan("", "", "", 0); len(kvs) != 4 {
+		t.Fatalf("RangeScan vraca %d kljuceva, ocekivano 4", len(kvs))
+	}
+	if kvs := sys.PrefixScan("", "b"); len(kvs) != 1 {
+		t.Fatalf("PrefixScan(b) vraca %d kljuceva, ocekivano 1", len(kvs))
+	}
+	if !sys.Delete("", "a") || sys.get("", "a") != nil {
+		t.Fatal("brisanje kljuca sa poslednjeg nivoa nije uspelo")
+	}
+}
+
+//Metadata bez zaglavlja (listovi su SHA-1 hash vrednosti) se proveravaju po starom obliku i ne prijavljuju se kao ostecenje
+func TestVerifyLegacyMetadata(t *testing.T) {
+	sys := newTestSystem(t)
+	sys.put("", "a", []byte("1"))
+	sys.put("", "b", []byte("2"))
+	sys.Flush()
+	writeLegacy := func(values ...string) {
+		leaves := make([]merkle_tree.Data, len(values))
+		for i, value := range values {
+			leaves[i] = merkle_tree.Data{Value: value}
+		}
+		root := merkle_tree.BuildMerkle(merkle_tree.ToLegacyNodeList(leaves), merkle_tree.SHA1)
+		data := []byte{}
+		for _, hash := range merkle_tree.TreeToList(root.Root) {
+			data = append(data, hash...)
+		}
+		if err := ioutil.WriteFile("data/SSTable1_1/metadata1_1.txt", data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeLegacy("1", "2")
+	mismatches, legacy, _, err := SSTable.VerifyAll(sys.config.max_height)
+	if err != nil || len(mismatches) != 0 || len(legacy) != 1 || legacy[0] != "1_1" {
+		t.Fatalf("ocekivana jedna tabela sa starim metadata, dobijeno %v, %v, %v", mismatches, legacy, err)
+	}
+	if _, _, _, err := SSTable.Prove("a", "1_1"); !errors.Is(err, merkle_tree.ErrLegacyMetadata) {
+		t.Fatalf("ocekivano %v, dobijeno %v", merkle_tree.ErrLegacyMetadata, err)
+	}
+
+	//Izmenjena vrednost je i dalje ostecenje
+	writeLegacy("1", "x")
+	mismatches, legacy, _, err = SSTable.VerifyAll(sys.config.max_height)
+	if err != nil || len(mismatches) != 1 || mismatches[0].Key != "b" || len(legacy) != 0 {
+		t.Fatalf("ocekivana razlika u kljucu b, dobijeno %v, %v, %v", mismatches, legacy, err)
+	}
+	if err := SSTable.RebuildLegacyMetadata("1_1"); err == nil {
+		t.Fatal("izgradjena su metadata tabele koja se ne poklapa sa starim stablom")
+	}
+
+	writeLegacy("1", "2")
+	if err := SSTable.RebuildLegacyMetadata("1_1"); err != nil {
+		t.Fatal(err)
+	}
+	mismatches, legacy, _, err = SSTable.VerifyAll(sys.config.max_height)
+	if err != nil || len(mismatches) != 0 || len(legacy) != 0 {
+		t.Fatalf("posle ponovne izgradnje dobijeno %v, %v, %v", mismatches, legacy, err)
+	}
+	if _, _, _, err := SSTable.Prove("a", "1_1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -52,6 +52,14 @@ func (m *Memtable) PutElement(input []byte) (bool, error) {
 	}
 }
 
+//Vraca da li je popunjenost memtabele dostigla prag posle kog se radi flush
+func (m *Memtable) Full() bool {
+	if m.curr_size == 0 {
+		return false
+	}
+	return float64(m.curr_size)/float64(m.max_size)*100 >= m.threshold
+}
+
 /*Funkcija brise podatak pod zadatim kljucem iz memtabele
 Vraca status izvrsenja*/
 func (m *Memtable) DeleteElement(key string) bool {
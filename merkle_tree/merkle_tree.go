@@ -1,25 +1,85 @@
 package merkle_tree
 
 import (
+	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"io/ioutil"
 	"os"
+	"strconv"
 )
 
-/*TODO:Prilagoditi sve ka Data segment formatu*/
+/*Blok podataka za jedan list - polja zapisa koja list pokriva
+CRC se izostavlja jer je izveden iz ostalih polja*/
 type Data struct {
-	/*crc [4]byte
-	timestamp [8]byte
-	tombstone [1]byte
-	value_size [8]byte
-	value []byte*/
-	Value string
+	Key       string
+	Value     string
+	Timestamp uint64
+	Tombstone byte
+}
+
+/*Hash funkcija kojom je stablo izgradjeno - upisuje se u zaglavlje metadata datoteke*/
+type Algorithm byte
+
+const (
+	SHA1   Algorithm = 1
+	SHA256 Algorithm = 2
+	SHA512 Algorithm = 3
+)
+
+/*Zaglavlje metadata datoteke: magic 4B i algoritam 1B*/
+var magic = []byte("MRKL")
+
+/*Metadata datoteka bez zaglavlja, iz vremena kada je list bio SHA-1 hash samo vrednosti zapisa
+Takvo stablo se ne moze porediti sa listovima koje pravi HashLeaf, pa ga treba ponovo izgraditi*/
+var ErrLegacyMetadata = errors.New("legacy metadata, rebuild required")
+
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch name {
+	case "sha1":
+		return SHA1, nil
+	case "sha256":
+		return SHA256, nil
+	case "sha512":
+		return SHA512, nil
+	}
+	return 0, errors.New("unknown merkle hash algorithm " + name)
+}
+
+func (algorithm Algorithm) String() string {
+	switch algorithm {
+	case SHA1:
+		return "sha1"
+	case SHA256:
+		return "sha256"
+	case SHA512:
+		return "sha512"
+	}
+	return "unknown(" + strconv.Itoa(int(algorithm)) + ")"
+}
+
+func (algorithm Algorithm) known() bool {
+	return algorithm == SHA1 || algorithm == SHA256 || algorithm == SHA512
+}
+
+/*Duzina hash vrednosti u bajtovima*/
+func (algorithm Algorithm) Size() int {
+	switch algorithm {
+	case SHA1:
+		return sha1.Size
+	case SHA512:
+		return sha512.Size
+	}
+	return sha256.Size
 }
 
 type MerkleRoot struct {
-	Root *Node
+	Root      *Node
+	Algorithm Algorithm
 }
 
 func (mr *MerkleRoot) String() string {
@@ -29,30 +89,42 @@ func (mr *MerkleRoot) String() string {
 /*Ukoliko je data prazan niz bajtova -> Node je prazan, tj. dodaje se zbog kompletnosti stabla*/
 /*data cuva hash vrednosti!*/
 type Node struct {
-	data  [20]byte
+	data  []byte
 	left  *Node
 	right *Node
 }
 
 func (n *Node) String() string {
-	return hex.EncodeToString(n.data[:])
+	return hex.EncodeToString(n.data)
 }
 
-func Hash(data []byte) [20]byte {
-	return sha1.Sum(data)
+func Hash(algorithm Algorithm, data []byte) []byte {
+	switch algorithm {
+	case SHA1:
+		sum := sha1.Sum(data)
+		return sum[:]
+	case SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
 }
 
 /*U slucaju da se hesiraju cvorovi koji nisu listovi*/
-func hash_pairs(node_1 *Node, node_2 *Node) [20]byte {
-	right_hash := []byte{}
-	if len(node_2.data) != 0 {
-		right_hash = node_2.data[:]
-	}
-	return Hash(append(node_1.data[:], right_hash[:]...))
+func hash_pairs(algorithm Algorithm, left []byte, right []byte) []byte {
+	pair := make([]byte, 0, len(left)+len(right))
+	pair = append(pair, left...)
+	pair = append(pair, right...)
+	return Hash(algorithm, pair)
 }
 
 /*Rekurzivno boottom-up konstruisanje merkle stabla */
-func BuildMerkle(data []Node) *MerkleRoot {
+func BuildMerkle(data []Node, algorithm Algorithm) *MerkleRoot {
+	if len(data) == 0 {
+		//Prazna tabela - stablo ima samo prazan koren
+		return &MerkleRoot{&Node{data: make([]byte, algorithm.Size())}, algorithm}
+	}
 	var nodes []Node
 	for i := 0; i < len(data); i += 2 {
 
@@ -61,29 +133,165 @@ func BuildMerkle(data []Node) *MerkleRoot {
 		if (i + 1) < len(data) {
 			right_node = data[i+1]
 		} else {
-			right_node = Node{data: [20]byte{}, left: nil, right: nil}
+			right_node = Node{data: make([]byte, algorithm.Size()), left: nil, right: nil}
 		}
-		nodes = append(nodes, Node{left: &left_node, right: &right_node, data: hash_pairs(&left_node, &right_node)})
+		nodes = append(nodes, Node{left: &left_node, right: &right_node, data: hash_pairs(algorithm, left_node.data, right_node.data)})
 	}
 	if len(nodes) == 1 {
-		return &MerkleRoot{&nodes[0]}
+		return &MerkleRoot{&nodes[0], algorithm}
 	} else {
-		return BuildMerkle(nodes)
+		return BuildMerkle(nodes, algorithm)
 	}
 }
 
 /*Funkcija uzima pocetne blokove podataka i pretvara ih u listove*/
-func ToNodeList(data []Data) []Node {
+func ToNodeList(data []Data, algorithm Algorithm) []Node {
+	var node_list = []Node{}
+	for _, elem := range data {
+		node_list = append(node_list, Node{left: nil, right: nil, data: HashLeaf(elem, algorithm)})
+	}
+	return node_list
+}
+
+/*Listovi u starom obliku (metadata bez zaglavlja): SHA-1 hash samo vrednosti zapisa*/
+func ToLegacyNodeList(data []Data) []Node {
 	var node_list = []Node{}
 	for _, elem := range data {
-		node_list = append(node_list, Node{left: nil, right: nil, data: Hash([]byte(elem.Value))})
+		node_list = append(node_list, Node{left: nil, right: nil, data: Hash(SHA1, []byte(elem.Value))})
 	}
 	return node_list
 }
 
+/*Pravi listove od vec izracunatih hash vrednosti (npr. hash-eva grupa zapisa)*/
+func HashNodeList(hashes [][]byte) []Node {
+	node_list := make([]Node, len(hashes))
+	for i, hash := range hashes {
+		node_list[i] = Node{data: hash}
+	}
+	return node_list
+}
+
+/*Hash vrednost lista za jedan blok podataka - klijent je racuna sam iz procitanog zapisa pri proveri dokaza
+List pokriva ceo zapis (kljuc, vrednost, timestamp i tombstone), pa se vrednosti ne mogu zameniti izmedju kljuceva
+Kljuc i vrednost se upisuju sa duzinom ispred, kako se granica izmedju njih ne bi mogla pomeriti*/
+func HashLeaf(elem Data, algorithm Algorithm) []byte {
+	bytes := make([]byte, 25+len(elem.Key)+len(elem.Value))
+	binary.LittleEndian.PutUint64(bytes[:8], uint64(len(elem.Key)))
+	copy(bytes[8:], elem.Key)
+	binary.LittleEndian.PutUint64(bytes[8+len(elem.Key):], uint64(len(elem.Value)))
+	copy(bytes[16+len(elem.Key):], elem.Value)
+	binary.LittleEndian.PutUint64(bytes[16+len(elem.Key)+len(elem.Value):], elem.Timestamp)
+	bytes[24+len(elem.Key)+len(elem.Value)] = elem.Tombstone
+	return Hash(algorithm, bytes)
+}
+
+/*Jedan korak dokaza - hash suseda i strana na kojoj se nalazi*/
+type Sibling struct {
+	Hash []byte
+	Left bool
+}
+
+/*Dokaz pripadnosti (inclusion proof) - hash vrednosti suseda od lista do korena*/
+type Proof struct {
+	Algorithm Algorithm
+	Leaf      int
+	Siblings  []Sibling
+}
+
+/*Pravi dokaz pripadnosti za list sa rednim brojem leaf
+Stablo se gradi nivo po nivo isto kao u BuildMerkle, pa je i koren isti
+Vraca dokaz i hash korena*/
+func BuildProof(data []Node, leaf int, algorithm Algorithm) (*Proof, []byte, error) {
+	if leaf < 0 || leaf >= len(data) {
+		return nil, nil, errors.New("leaf " + strconv.Itoa(leaf) + " out of range")
+	}
+	level := make([][]byte, len(data))
+	for i, node := range data {
+		level[i] = node.data
+	}
+	empty := make([]byte, algorithm.Size())
+	proof := &Proof{Algorithm: algorithm, Leaf: leaf}
+	index := leaf
+	for next := true; next; next = len(level) > 1 {
+		//Nedostajuci desni cvor je prazan cvor za dopunu, kao u BuildMerkle
+		sibling := Sibling{Left: index%2 == 1, Hash: empty}
+		if sibling.Left {
+			sibling.Hash = level[index-1]
+		} else if index+1 < len(level) {
+			sibling.Hash = level[index+1]
+		}
+		proof.Siblings = append(proof.Siblings, sibling)
+
+		var parents [][]byte
+		for i := 0; i < len(level); i += 2 {
+			right := empty
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			parents = append(parents, hash_pairs(algorithm, level[i], right))
+		}
+		level = parents
+		index /= 2
+	}
+	return proof, level[0], nil
+}
+
+/*Samostalna provera dokaza - ne zahteva pristup stablu ni bazi
+leaf je hash lista (HashLeaf procitanog zapisa algoritmom iz dokaza), root je potpisani hash korena tabele*/
+func VerifyProof(leaf []byte, proof *Proof, root []byte) bool {
+	current := leaf
+	for _, sibling := range proof.Siblings {
+		if sibling.Left {
+			current = hash_pairs(proof.Algorithm, sibling.Hash, current)
+		} else {
+			current = hash_pairs(proof.Algorithm, current, sibling.Hash)
+		}
+	}
+	return bytes.Equal(current, root)
+}
+
+/*Serijalizacija dokaza: algoritam 1B, redni broj lista 8B, broj suseda 4B,
+pa za svakog suseda strana 1B i hash (duzina zavisi od algoritma)*/
+func (proof *Proof) Marshal() []byte {
+	size := proof.Algorithm.Size()
+	bytes := make([]byte, 13, 13+len(proof.Siblings)*(size+1))
+	bytes[0] = byte(proof.Algorithm)
+	binary.LittleEndian.PutUint64(bytes[1:9], uint64(proof.Leaf))
+	binary.LittleEndian.PutUint32(bytes[9:13], uint32(len(proof.Siblings)))
+	for _, sibling := range proof.Siblings {
+		side := byte(0)
+		if sibling.Left {
+			side = 1
+		}
+		bytes = append(bytes, side)
+		bytes = append(bytes, sibling.Hash...)
+	}
+	return bytes
+}
+
+func UnmarshalProof(bytes []byte) (*Proof, error) {
+	if len(bytes) < 13 {
+		return nil, errors.New("proof too short")
+	}
+	proof := &Proof{Algorithm: Algorithm(bytes[0]), Leaf: int(binary.LittleEndian.Uint64(bytes[1:9]))}
+	if !proof.Algorithm.known() {
+		return nil, errors.New("proof uses unknown hash algorithm")
+	}
+	size := proof.Algorithm.Size()
+	count := int(binary.LittleEndian.Uint32(bytes[9:13]))
+	if len(bytes) != 13+count*(size+1) {
+		return nil, errors.New("proof length does not match sibling count")
+	}
+	for i := 0; i < count; i++ {
+		part := bytes[13+i*(size+1) : 13+(i+1)*(size+1)]
+		proof.Siblings = append(proof.Siblings, Sibling{Left: part[0] == 1, Hash: part[1:]})
+	}
+	return proof, nil
+}
+
 /*Breadth first obilazak stabla pocevsi od njegovog korena i pretvaranja stabla u niz hash vrednosti*/
-func TreeToList(root *Node) [][20]byte {
-	list := [][20]byte{}
+func TreeToList(root *Node) [][]byte {
+	list := [][]byte{}
 	var queue = []*Node{root}
 	for true {
 		if len(queue) == 0 {
@@ -108,37 +316,137 @@ func TreeToList(root *Node) [][20]byte {
 
 /*Serijalizacija stabla u datoteku
 file_name -> target datoteka
-tree_list -> breadth - first obidjeno merkle stablo*/
-func Serialize(tree_list [][20]byte, file_name string) {
-	file, err := os.OpenFile(file_name, os.O_CREATE|os.O_WRONLY, 0777)
+tree_list -> breadth - first obidjeno merkle stablo
+Na pocetak se upisuje zaglavlje sa algoritmom kojim je stablo izgradjeno*/
+func Serialize(tree_list [][]byte, algorithm Algorithm, file_name string) {
+	file, err := os.OpenFile(file_name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
 	if err != nil {
 		panic(err.Error())
 	}
 	defer file.Close()
-	error := binary.Write(file, binary.LittleEndian, tree_list)
+	data := make([]byte, 0, len(magic)+1+len(tree_list)*algorithm.Size())
+	data = append(data, magic...)
+	data = append(data, byte(algorithm))
+	for _, hash := range tree_list {
+		data = append(data, hash...)
+	}
+	_, error := file.Write(data)
 	if error != nil {
 		panic(error.Error())
 	}
 }
 
 /*Deserijalizacija stabla is target file_name datoteke
-Sluzi kao provera*/
-func deserialize(file_name string) [][]byte {
+Vraca breadth - first listu hash vrednosti u istom obliku u kom je upisana i algoritam iz zaglavlja
+Za datoteku bez zaglavlja (pre uvodjenja izbora algoritma) vraca listu, SHA1 i ErrLegacyMetadata -
+listovi takvog stabla se proveravaju sa ToLegacyNodeList*/
+func Deserialize(file_name string) ([][]byte, Algorithm, error) {
 	var result_list [][]byte
 	file, err := os.OpenFile(file_name, os.O_RDONLY, 0777)
 	if err != nil {
-		panic(err.Error())
+		return nil, 0, err
 	}
 	defer file.Close()
-	data, error := ioutil.ReadAll(file)
-	if error != nil {
-		panic(error.Error())
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	algorithm := SHA1
+	legacy := true
+	if len(data) >= len(magic)+1 && bytes.Equal(data[:len(magic)], magic) {
+		algorithm = Algorithm(data[len(magic)])
+		if !algorithm.known() {
+			return nil, 0, errors.New("metadata file " + file_name + " uses unknown hash algorithm")
+		}
+		data = data[len(magic)+1:]
+		legacy = false
+	}
+	size := algorithm.Size()
+	if len(data)%size != 0 {
+		return nil, 0, errors.New("metadata file " + file_name + " is not a list of hashes")
 	}
 
-	for i := 0; i < len(data)/20; i++ {
-		result_list = append(result_list, data[i*20:i*20+20])
+	for i := 0; i < len(data)/size; i++ {
+		result_list = append(result_list, data[i*size:i*size+size])
+	}
+	if legacy {
+		return result_list, algorithm, ErrLegacyMetadata
+	}
+	return result_list, algorithm, nil
+}
+
+/*Poredi stablo izgradjeno iz podataka sa sacuvanom (deserijalizovanom) listom
+Spusta se samo u podstabla ciji se hash razlikuje i vraca redne brojeve listova (zapisa) koji se razlikuju
+Ukoliko se oblik stabla razlikuje (drugaciji broj listova), vraca gresku*/
+func Diff(root *Node, stored [][]byte) ([]int, error) {
+	return diffShape(root, TreeToList(root), stored)
+}
+
+/*Poredi dve sacuvane liste stabala sa istim brojem listova (npr. iste tabele na dve replike)
+Oblik stabla zavisi samo od broja listova, pa se gradi od praznih listova
+Obe liste moraju biti izgradjene istim algoritmom*/
+func DiffLists(first [][]byte, second [][]byte, leaves int) ([]int, error) {
+	shape := BuildMerkle(make([]Node, leaves), SHA1)
+	return diffShape(shape.Root, first, second)
+}
+
+/*Spusta se kroz oblik stabla i poredi hash vrednosti iz dve breadth first liste na istim pozicijama*/
+func diffShape(root *Node, first [][]byte, second [][]byte) ([]int, error) {
+	//Breadth first obilazak daje isti redosled kao TreeToList, pa je indeks cvora ujedno i indeks u listama
+	position := make(map[*Node]int)
+	depth := make(map[*Node]int)
+	var order []*Node
+	var queue = []*Node{root}
+	depth[root] = 0
+	for len(queue) != 0 {
+		node := queue[0]
+		queue = queue[1:]
+		position[node] = len(order)
+		order = append(order, node)
+		for _, child := range []*Node{node.left, node.right} {
+			if child != nil {
+				depth[child] = depth[node] + 1
+				queue = append(queue, child)
+			}
+		}
+	}
+	if len(order) != len(first) || len(order) != len(second) {
+		return nil, errors.New("merkle tree shape differs: " + strconv.Itoa(len(first)) + " and " + strconv.Itoa(len(second)) + " nodes, expected " + strconv.Itoa(len(order)))
+	}
+
+	//Listovi su cvorovi na najdubljem nivou, redom kojim su zapisi dodati (poslednji moze biti prazan cvor za dopunu)
+	height := depth[order[len(order)-1]]
+	leaf := make(map[*Node]int)
+	for _, node := range order {
+		if depth[node] == height {
+			leaf[node] = len(leaf)
+		}
+	}
+
+	var diff []int
+	var descend func(node *Node)
+	descend = func(node *Node) {
+		if bytes.Equal(first[position[node]], second[position[node]]) {
+			return
+		}
+		if i, is_leaf := leaf[node]; is_leaf {
+			diff = append(diff, i)
+			return
+		}
+		found := len(diff)
+		if node.left != nil {
+			descend(node.left)
+		}
+		if node.right != nil {
+			descend(node.right)
+		}
+		if found == len(diff) {
+			//Deca se poklapaju (ili cvor nema decu) - ostecena je sama metadata datoteka
+			diff = append(diff, -1)
+		}
 	}
-	return result_list
+	descend(root)
+	return diff, nil
 }
 
 /*func main(){
@@ -0,0 +1,193 @@
+package merkle_tree
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var algorithms = []Algorithm{SHA1, SHA256, SHA512}
+
+func testData(n int) []Data {
+	data := make([]Data, n)
+	for i := range data {
+		data[i] = Data{Key: "k" + strconv.Itoa(i), Value: "v" + strconv.Itoa(i), Timestamp: uint64(i)}
+	}
+	return data
+}
+
+//Dokaz za svaki list, i kod neparnog broja listova, proverava se posle serijalizacije
+func TestProofRoundTrip(t *testing.T) {
+	for _, algorithm := range algorithms {
+		for _, n := range []int{1, 2, 3, 5, 7, 8, 13} {
+			data := testData(n)
+			root := BuildMerkle(ToNodeList(data, algorithm), algorithm).Root.data
+			for leaf := range data {
+				proof, proofRoot, err := BuildProof(ToNodeList(data, algorithm), leaf, algorithm)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(proofRoot, root) {
+					t.Fatalf("%v, %d listova: koren dokaza se razlikuje od korena stabla", algorithm, n)
+				}
+				decoded, err := UnmarshalProof(proof.Marshal())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(decoded, proof) {
+					t.Fatalf("%v, %d listova, list %d: dokaz se menja serijalizacijom", algorithm, n, leaf)
+				}
+				if !VerifyProof(HashLeaf(data[leaf], algorithm), decoded, root) {
+					t.Fatalf("%v, %d listova: dokaz za list %d nije prihvacen", algorithm, n, leaf)
+				}
+			}
+		}
+	}
+	if _, _, err := BuildProof(ToNodeList(testData(3), SHA256), 3, SHA256); err == nil {
+		t.Fatal("ocekivana greska za list van opsega")
+	}
+}
+
+//Izmenjen zapis, sused, strana suseda ili koren obaraju proveru
+func TestTamperedProof(t *testing.T) {
+	for _, algorithm := range algorithms {
+		data := testData(6)
+		proof, root, err := BuildProof(ToNodeList(data, algorithm), 2, algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf := HashLeaf(data[2], algorithm)
+		tampered := data[2]
+		tampered.Value = "x"
+		if VerifyProof(HashLeaf(tampered, algorithm), proof, root) {
+			t.Errorf("%v: prihvacena izmenjena vrednost", algorithm)
+		}
+		tampered = data[2]
+		tampered.Tombstone = 1
+		if VerifyProof(HashLeaf(tampered, algorithm), proof, root) {
+			t.Errorf("%v: prihvacen izmenjen tombstone", algorithm)
+		}
+		if VerifyProof(HashLeaf(data[3], algorithm), proof, root) {
+			t.Errorf("%v: prihvacen drugi zapis", algorithm)
+		}
+
+		for i := range proof.Siblings {
+			changed, _ := UnmarshalProof(proof.Marshal())
+			changed.Siblings[i].Hash[0] ^= 1
+			if VerifyProof(leaf, changed, root) {
+				t.Errorf("%v: prihvacen izmenjen sused %d", algorithm, i)
+			}
+			changed, _ = UnmarshalProof(proof.Marshal())
+			changed.Siblings[i].Left = !changed.Siblings[i].Left
+			if VerifyProof(leaf, changed, root) {
+				t.Errorf("%v: prihvacena zamenjena strana suseda %d", algorithm, i)
+			}
+		}
+		short := &Proof{Algorithm: algorithm, Leaf: 2, Siblings: proof.Siblings[:len(proof.Siblings)-1]}
+		if VerifyProof(leaf, short, root) {
+			t.Errorf("%v: prihvacen skracen dokaz", algorithm)
+		}
+		if VerifyProof(leaf, proof, Hash(algorithm, []byte("drugi koren"))) {
+			t.Errorf("%v: prihvacen drugi koren", algorithm)
+		}
+
+		encoded := proof.Marshal()
+		if _, err := UnmarshalProof(encoded[:len(encoded)-1]); err == nil {
+			t.Errorf("%v: prihvacen odsecen dokaz", algorithm)
+		}
+		encoded[0] = 9
+		if _, err := UnmarshalProof(encoded); err == nil {
+			t.Errorf("%v: prihvacen nepoznat algoritam", algorithm)
+		}
+	}
+}
+
+//Diff vraca redne brojeve izmenjenih listova, i kada poslednji list nema para
+func TestDiff(t *testing.T) {
+	for _, algorithm := range algorithms {
+		for _, n := range []int{1, 2, 5, 7, 8} {
+			data := testData(n)
+			stored := TreeToList(BuildMerkle(ToNodeList(data, algorithm), algorithm).Root)
+			diff, err := Diff(BuildMerkle(ToNodeList(data, algorithm), algorithm).Root, stored)
+			if err != nil || len(diff) != 0 {
+				t.Fatalf("%v, %d listova: ocekivano bez razlika, dobijeno %v, %v", algorithm, n, diff, err)
+			}
+
+			expected := []int{0, n - 1}
+			if n == 1 {
+				expected = []int{0}
+			}
+			for _, leaf := range expected {
+				data[leaf].Value += "!"
+			}
+			diff, err = Diff(BuildMerkle(ToNodeList(data, algorithm), algorithm).Root, stored)
+			if err != nil || !reflect.DeepEqual(diff, expected) {
+				t.Fatalf("%v, %d listova: ocekivano %v, dobijeno %v, %v", algorithm, n, expected, diff, err)
+			}
+		}
+	}
+
+	//Drugaciji broj listova je drugaciji oblik stabla
+	algorithm := SHA256
+	stored := TreeToList(BuildMerkle(ToNodeList(testData(4), algorithm), algorithm).Root)
+	if _, err := Diff(BuildMerkle(ToNodeList(testData(5), algorithm), algorithm).Root, stored); err == nil {
+		t.Fatal("ocekivana greska za razlicit oblik stabla")
+	}
+	//Ostecen koren, a listovi se poklapaju
+	stored[0] = Hash(algorithm, []byte("ostecen"))
+	diff, err := Diff(BuildMerkle(ToNodeList(testData(4), algorithm), algorithm).Root, stored)
+	if err != nil || !reflect.DeepEqual(diff, []int{-1}) {
+		t.Fatalf("ocekivano [-1], dobijeno %v, %v", diff, err)
+	}
+}
+
+//DiffLists poredi dve sacuvane liste bez podataka, oblik stabla zavisi samo od broja listova
+func TestDiffLists(t *testing.T) {
+	for _, algorithm := range algorithms {
+		first := testData(9)
+		second := testData(9)
+		second[4].Timestamp++
+		second[8].Key = "drugi"
+		firstList := TreeToList(BuildMerkle(ToNodeList(first, algorithm), algorithm).Root)
+		secondList := TreeToList(BuildMerkle(ToNodeList(second, algorithm), algorithm).Root)
+		diff, err := DiffLists(firstList, secondList, 9)
+		if err != nil || !reflect.DeepEqual(diff, []int{4, 8}) {
+			t.Fatalf("%v: ocekivano [4 8], dobijeno %v, %v", algorithm, diff, err)
+		}
+		if _, err := DiffLists(firstList, secondList, 17); err == nil {
+			t.Fatalf("%v: ocekivana greska za pogresan broj listova", algorithm)
+		}
+	}
+}
+
+//Zaglavlje cuva algoritam, a datoteka bez zaglavlja je staro (SHA-1) stablo
+func TestSerialize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "merkle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metadata.txt")
+	for _, algorithm := range algorithms {
+		list := TreeToList(BuildMerkle(ToNodeList(testData(5), algorithm), algorithm).Root)
+		Serialize(list, algorithm, path)
+		loaded, loadedAlgorithm, err := Deserialize(path)
+		if err != nil || loadedAlgorithm != algorithm || !reflect.DeepEqual(loaded, list) {
+			t.Fatalf("%v: dobijeno %v, %v", algorithm, loadedAlgorithm, err)
+		}
+	}
+
+	list := TreeToList(BuildMerkle(ToLegacyNodeList(testData(3)), SHA1).Root)
+	if err := ioutil.WriteFile(path, bytes.Join(list, nil), 0666); err != nil {
+		t.Fatal(err)
+	}
+	loaded, algorithm, err := Deserialize(path)
+	if !errors.Is(err, ErrLegacyMetadata) || algorithm != SHA1 || !reflect.DeepEqual(loaded, list) {
+		t.Fatalf("ocekivano staro SHA-1 stablo, dobijeno %v, %v", algorithm, err)
+	}
+}
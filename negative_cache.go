@@ -0,0 +1,101 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*Negativni cache pamti kljuceve za koje je get potvrdio da ne postoje (ni u memtabeli ni u SSTabelama),
+pa ponovljeno trazenje takvog kljuca ne prolazi kroz sve nivoe i filtere
+Stavka vazi najvise ttl i ima ih najvise max_items, a put kljuca je odmah brise
+nil NegativeCache je iskljucen cache - sve metode rade i nad nil vrednoscu*/
+type NegativeCache struct {
+	mutex     sync.Mutex
+	max_items int
+	ttl       time.Duration
+	entries   map[string]*list.Element
+	order     *list.List //na pocetku je najstariji promasaj
+	hits      uint64
+	added     uint64
+}
+
+type negativeEntry struct {
+	key     string
+	expires time.Time
+}
+
+/*Kreira negativni cache, za max_items ili ttl jednak 0 vraca nil (iskljucen)*/
+func createNegativeCache(max_items int, ttl time.Duration) *NegativeCache {
+	if max_items <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &NegativeCache{
+		max_items: max_items,
+		ttl:       ttl,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+/*Da li je kljuc nedavno potvrdjen kao nepostojeci*/
+func (cache *NegativeCache) Contains(key string) bool {
+	if cache == nil {
+		return false
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, is_present := cache.entries[key]
+	if !is_present {
+		return false
+	}
+	if time.Now().After(element.Value.(*negativeEntry).expires) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return false
+	}
+	cache.hits++
+	return true
+}
+
+/*Pamti potvrdjen promasaj, najstariji promasaj se izbacuje kada je cache pun*/
+func (cache *NegativeCache) Add(key string) {
+	if cache == nil {
+		return
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, is_present := cache.entries[key]; is_present {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+	for cache.order.Len() >= cache.max_items {
+		oldest := cache.order.Remove(cache.order.Front()).(*negativeEntry)
+		delete(cache.entries, oldest.key)
+	}
+	cache.entries[key] = cache.order.PushBack(&negativeEntry{key: key, expires: time.Now().Add(cache.ttl)})
+	cache.added++
+}
+
+/*Brise kljuc - poziva se kada se kljuc upise*/
+func (cache *NegativeCache) Invalidate(key string) {
+	if cache == nil {
+		return
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, is_present := cache.entries[key]; is_present {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+}
+
+func (cache *NegativeCache) String() string {
+	if cache == nil {
+		return "negative cache: off"
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return fmt.Sprintf("negative cache: %d hits, %d misses remembered, %d/%d items", cache.hits, cache.added, cache.order.Len(), cache.max_items)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*RESP2 - protokol kojim Redis klijenti razgovaraju sa serverom
+Komanda je niz bulk stringova (*2\r\n$3\r\nGET\r\n$1\r\nk\r\n), ili za rucno kucanje jedan red reci (inline komanda)
+Odgovor je jednostavan string (+), greska (-), ceo broj (:), bulk string ($, $-1 je nil) ili niz (*)*/
+
+//Najveci bulk string i najvise elemenata niza koji se prihvataju
+const respMaxBulk = 16 << 20
+const respMaxArray = 1 << 20
+
+//Najveca dubina ugnjezdenih nizova u odgovoru koju klijent prihvata
+const respMaxDepth = 32
+
+//Odgovor sa greskom (-ERR ...)
+type respError string
+
+func (err respError) Error() string {
+	return string(err)
+}
+
+//Odgovor sa jednostavnim stringom (+OK)
+type respSimple string
+
+var errRESPProtocol = errors.New("ERR Protocol error")
+
+/*Cita jednu komandu klijenta: niz bulk stringova ili inline red
+Niz mora biti ravan i sadrzati samo bulk stringove, pa se ugnjezdeni nizovi odbijaju pre citanja
+Prazan inline red vraca praznu komandu*/
+func readRESPCommand(reader *bufio.Reader) ([][]byte, error) {
+	line, err := readRESPLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		args := [][]byte{}
+		for _, field := range strings.Fields(line) {
+			args = append(args, []byte(field))
+		}
+		return args, nil
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 || count > respMaxArray {
+		return nil, errRESPProtocol
+	}
+	args := make([][]byte, 0)
+	for i := 0; i < count; i++ {
+		line, err = readRESPLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errRESPProtocol
+		}
+		bulk, err := readRESPBulk(reader, line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if bulk == nil {
+			return nil, errRESPProtocol
+		}
+		args = append(args, bulk)
+	}
+	return args, nil
+}
+
+/*Cita jednu vrednost: respSimple, respError, int64, []byte ([]byte(nil) za nil bulk) ili []interface{}
+Greska citanja ili neispravan zapis prekida vezu, respError je regularan odgovor*/
+func readRESP(reader *bufio.Reader) (interface{}, error) {
+	return readRESPValue(reader, 0)
+}
+
+func readRESPValue(reader *bufio.Reader, depth int) (interface{}, error) {
+	line, err := readRESPLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errRESPProtocol
+	}
+	switch line[0] {
+	case '+':
+		return respSimple(line[1:]), nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, errRESPProtocol
+		}
+		return n, nil
+	case '$':
+		return readRESPBulk(reader, line[1:])
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < -1 || count > respMaxArray || depth >= respMaxDepth {
+			return nil, errRESPProtocol
+		}
+		if count == -1 {
+			return []interface{}(nil), nil
+		}
+		array := make([]interface{}, 0)
+		for i := 0; i < count; i++ {
+			element, err := readRESPValue(reader, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, element)
+		}
+		return array, nil
+	}
+	return nil, errRESPProtocol
+}
+
+//Cita telo bulk stringa cija je duzina zadata u zaglavlju, -1 je nil
+func readRESPBulk(reader *bufio.Reader, header string) ([]byte, error) {
+	size, err := strconv.Atoi(header)
+	if err != nil || size < -1 || size > respMaxBulk {
+		return nil, errRESPProtocol
+	}
+	if size == -1 {
+		return []byte(nil), nil
+	}
+	bulk := make([]byte, size+2)
+	if _, err = io.ReadFull(reader, bulk); err != nil {
+		return nil, err
+	}
+	if bulk[size] != '\r' || bulk[size+1] != '\n' {
+		return nil, errRESPProtocol
+	}
+	return bulk[:size], nil
+}
+
+//Cita red bez zavrsnog \r\n, deo po deo, pa red duzi od respMaxBulk prekida vezu pre nego sto se ceo ucita
+func readRESPLine(reader *bufio.Reader) (string, error) {
+	line := make([]byte, 0)
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line)+len(chunk) > respMaxBulk {
+			return "", errRESPProtocol
+		}
+		line = append(line, chunk...)
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+/*Upisuje vrednost u RESP formatu, prihvata iste tipove koje vraca readRESP, kao i string (bulk), int, nil i [][]byte
+Greska upisa ostaje u writer-u i vraca se pri Flush*/
+func writeRESP(writer *bufio.Writer, value interface{}) {
+	switch value := value.(type) {
+	case nil:
+		writer.WriteString("$-1\r\n")
+	case respSimple:
+		writer.WriteString("+" + string(value) + "\r\n")
+	case respError:
+		writer.WriteString("-" + string(value) + "\r\n")
+	case int:
+		writer.WriteString(":" + strconv.Itoa(value) + "\r\n")
+	case int64:
+		writer.WriteString(":" + strconv.FormatInt(value, 10) + "\r\n")
+	case string:
+		writeRESP(writer, []byte(value))
+	case []byte:
+		if value == nil {
+			writer.WriteString("$-1\r\n")
+			return
+		}
+		writer.WriteString("$" + strconv.Itoa(len(value)) + "\r\n")
+		writer.Write(value)
+		writer.WriteString("\r\n")
+	case [][]byte:
+		writer.WriteString("*" + strconv.Itoa(len(value)) + "\r\n")
+		for _, element := range value {
+			writeRESP(writer, element)
+		}
+	case []interface{}:
+		if value == nil {
+			writer.WriteString("*-1\r\n")
+			return
+		}
+		writer.WriteString("*" + strconv.Itoa(len(value)) + "\r\n")
+		for _, element := range value {
+			writeRESP(writer, element)
+		}
+	default:
+		panic(fmt.Sprintf("resp: nepodrzan tip %T", value))
+	}
+}
+
+//Ispisuje odgovor kao redis-cli
+func formatRESP(value interface{}, indent string) string {
+	switch value := value.(type) {
+	case respSimple:
+		return string(value)
+	case respError:
+		return "(error) " + string(value)
+	case int64:
+		return "(integer) " + strconv.FormatInt(value, 10)
+	case []byte:
+		if value == nil {
+			return "(nil)"
+		}
+		//Visered tekst (INFO) se ispisuje kakav jeste
+		if strings.Contains(string(value), "\r\n") {
+			return strings.TrimRight(strings.ReplaceAll(string(value), "\r\n", "\n"), "\n")
+		}
+		return strconv.Quote(string(value))
+	case []interface{}:
+		if len(value) == 0 {
+			return "(empty array)"
+		}
+		lines := make([]string, len(value))
+		for i, element := range value {
+			prefix := strconv.Itoa(i+1) + ") "
+			lines[i] = prefix + formatRESP(element, indent+strings.Repeat(" ", len(prefix)))
+		}
+		return strings.Join(lines, "\n"+indent)
+	}
+	return fmt.Sprint(value)
+}
+
+//Veza klijenta sa RESP serverom
+type respClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func dialRESP(addr string) (*respClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &respClient{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}, nil
+}
+
+//Salje jednu komandu kao niz bulk stringova i vraca odgovor servera
+func (client *respClient) do(args ...string) (interface{}, error) {
+	command := make([][]byte, len(args))
+	for i, arg := range args {
+		command[i] = []byte(arg)
+	}
+	writeRESP(client.writer, command)
+	if err := client.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return readRESP(client.reader)
+}
+
+func (client *respClient) Close() error {
+	return client.conn.Close()
+}
+
+//Komanda redis [--addr adresa] <komanda> [argumenti] - salje jednu komandu RESP serveru i ispisuje odgovor
+//Vraca izlazni kod procesa: 0 = odgovor nije greska, 1 = server je vratio gresku ili veza nije uspela, 2 = neispravni argumenti
+func redisClient(args []string) int {
+	flags := flag.NewFlagSet("redis", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:6379", "adresa RESP servera")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Println("upotreba: redis [--addr adresa] <komanda> [argumenti]")
+		return 2
+	}
+	client, err := dialRESP(*addr)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer client.Close()
+
+	reply, err := client.do(flags.Args()...)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Println(formatRESP(reply, ""))
+	if _, failed := reply.(respError); failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"kv/SSTable"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+/*Redis (RESP2) server nad sistemom
+Podrzane komande: GET, SET (EX/PX), DEL, EXISTS, MGET, MSET, SCAN (MATCH/COUNT), PING, INFO, COMMAND i QUIT,
+kao i komande za vrednosti sa tipom: BF.RESERVE, BF.ADD, BF.MADD, BF.EXISTS, PFADD, PFCOUNT, PFMERGE,
+CMS.INITBYPROB, CMS.INCRBY, CMS.QUERY, CMS.MERGE, DOC.SET i DOC.SIMILAR (resp_values.go)
+Svaka komanda se izvrsava dok se drzi brava sistema, pa je MSET vidljiv drugim klijentima odjednom
+Vreme isteka (SET EX/PX) cuva sistem (expire.go). Istekao kljuc se brise kroz Delete, pa se izbacuje i iz cache-a -
+pri pristupu kljucu i periodicno, jednom u sekundi*/
+type respServer struct {
+	sys     *System
+	addr    string
+	started time.Time
+
+	//Otvorene veze, koriste se samo dok se drzi conn_mutex
+	conn_mutex sync.Mutex
+	open       map[net.Conn]bool
+	closing    bool
+	listener   net.Listener
+
+	//Brojaci za INFO, menjaju se atomicno
+	clients     int64
+	connections int64
+	commands    int64
+}
+
+func newRESPServer(sys *System, listener net.Listener) *respServer {
+	return &respServer{
+		sys:      sys,
+		addr:     listener.Addr().String(),
+		started:  time.Now(),
+		open:     make(map[net.Conn]bool),
+		listener: listener,
+	}
+}
+
+//Komanda resp [--addr adresa] [--diff-socket putanja] - pokrece Redis server, SIGINT ili SIGTERM ga uredno gase
+func serveRESP(args []string) int {
+	flags := flag.NewFlagSet("resp", flag.ContinueOnError)
+	addr := flags.String("addr", ":6379", "adresa na kojoj server slusa")
+	diffSocket := flags.String("diff-socket", "", "lokalni socket na kome se izlaze baza za diff")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Println("upotreba: resp [--addr adresa] [--diff-socket putanja]")
+		return 2
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if !openSystem() {
+		listener.Close()
+		return 1
+	}
+	stopDiff, err := system.serveDiff(*diffSocket)
+	if err != nil {
+		fmt.Println(err)
+		listener.Close()
+		closeSystem()
+		return 1
+	}
+	server := newRESPServer(system, listener)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.shutdown()
+	}()
+
+	fmt.Println("RESP server slusa na " + server.addr)
+	server.serve()
+	//Zapoceta komanda je zavrsena, pa se tek sada upisuje ostatak WAL-a
+	status := 0
+	stopDiff()
+	if closeSystem() != 0 {
+		status = 1
+	}
+	return status
+}
+
+//Prihvata veze dok se ne pozove shutdown i vraca se kada se sve veze zatvore
+func (server *respServer) serve() {
+	var connections sync.WaitGroup
+	stop := make(chan bool)
+	go server.expireLoop(stop)
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			server.conn_mutex.Lock()
+			stopped := server.closing
+			server.conn_mutex.Unlock()
+			if !stopped {
+				fmt.Println(err)
+			}
+			break
+		}
+		server.conn_mutex.Lock()
+		if server.closing {
+			server.conn_mutex.Unlock()
+			conn.Close()
+			break
+		}
+		server.open[conn] = true
+		connections.Add(1)
+		server.conn_mutex.Unlock()
+		go func() {
+			defer connections.Done()
+			server.handle(conn)
+			server.conn_mutex.Lock()
+			delete(server.open, conn)
+			server.conn_mutex.Unlock()
+		}()
+	}
+	connections.Wait()
+	close(stop)
+}
+
+//Zatvaranje listener-a prekida Accept, a istekli rok citanja budi veze koje cekaju sledecu komandu
+func (server *respServer) shutdown() {
+	server.conn_mutex.Lock()
+	server.closing = true
+	for conn := range server.open {
+		conn.SetReadDeadline(time.Now())
+	}
+	server.conn_mutex.Unlock()
+	server.listener.Close()
+}
+
+//Obradjuje komande jedne veze dok je klijent ne zatvori, posalje QUIT ili se server ne ugasi
+//Panika pri obradi komande zatvara samo tu vezu, a brava sistema se oslobadja u run
+func (server *respServer) handle(conn net.Conn) {
+	defer conn.Close()
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("resp:", conn.RemoteAddr(), r)
+		}
+	}()
+	atomic.AddInt64(&server.clients, 1)
+	atomic.AddInt64(&server.connections, 1)
+	defer atomic.AddInt64(&server.clients, -1)
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		args, err := readRESPCommand(reader)
+		if err != nil {
+			if err == errRESPProtocol {
+				writeRESP(writer, respError(err.Error()))
+				writer.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		atomic.AddInt64(&server.commands, 1)
+		name := strings.ToUpper(string(args[0]))
+		if name == "QUIT" {
+			writeRESP(writer, respSimple("OK"))
+			writer.Flush()
+			return
+		}
+		writeRESP(writer, server.run(name, args[1:]))
+		//Odgovori na komande koje je klijent poslao zajedno (pipelining) salju se zajedno
+		if reader.Buffered() == 0 {
+			if writer.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+//Izvrsava komandu dok se drzi brava sistema
+func (server *respServer) run(name string, args [][]byte) interface{} {
+	server.sys.mutex.Lock()
+	defer server.sys.mutex.Unlock()
+	return server.execute(name, args)
+}
+
+func (server *respServer) execute(name string, args [][]byte) interface{} {
+	sys := server.sys
+	if reply := checkKeyArgs(name, args); reply != nil {
+		return reply
+	}
+	switch name {
+	case "PING":
+		if len(args) > 1 {
+			return wrongArgs(name)
+		}
+		if len(args) == 1 {
+			return args[0]
+		}
+		return respSimple("PONG")
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return server.get(string(args[0]))
+	case "SET":
+		if len(args) < 2 {
+			return wrongArgs(name)
+		}
+		ttl, reply := parseExpire(args[2:])
+		if reply != nil {
+			return reply
+		}
+		if !server.set(string(args[0]), args[1], ttl) {
+			return respError("ERR write failed")
+		}
+		return respSimple("OK")
+	case "MSET":
+		if len(args) == 0 || len(args)%2 != 0 {
+			return wrongArgs(name)
+		}
+		for i := 0; i < len(args); i += 2 {
+			if !server.set(string(args[i]), args[i+1], 0) {
+				return respError("ERR write failed")
+			}
+		}
+		return respSimple("OK")
+	case "MGET":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		values := make([][]byte, len(args))
+		for i, key := range args {
+			values[i] = server.get(string(key))
+		}
+		return values
+	case "DEL":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		deleted := 0
+		for _, key := range args {
+			if sys.expire(string(key)) {
+				continue
+			}
+			if sys.Delete("", string(key)) {
+				deleted++
+			}
+		}
+		return deleted
+	case "EXISTS":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		found := 0
+		for _, key := range args {
+			if server.get(string(key)) != nil {
+				found++
+			}
+		}
+		return found
+	case "SCAN":
+		return server.scan(args)
+	case "BF.RESERVE", "BF.ADD", "BF.MADD", "BF.EXISTS":
+		return server.bloom(name, args)
+	case "PFADD", "PFCOUNT", "PFMERGE":
+		return server.hll(name, args)
+	case "CMS.INITBYPROB", "CMS.INCRBY", "CMS.QUERY", "CMS.MERGE":
+		return server.cms(name, args)
+	case "DOC.SET", "DOC.SIMILAR":
+		return server.document(name, args)
+	case "INFO":
+		if len(args) > 1 {
+			return wrongArgs(name)
+		}
+		section := ""
+		if len(args) == 1 {
+			section = strings.ToLower(string(args[0]))
+		}
+		return server.info(section)
+	case "COMMAND":
+		//redis-cli pri povezivanju trazi opis komandi, prazan odgovor je dovoljan
+		return []interface{}{}
+	}
+	return respError("ERR unknown command '" + strings.ToLower(name) + "'")
+}
+
+//Odbija komandu ciji je neki kljuc iz rezervisanog dela prostora kljuceva, pre nego sto se bilo sta izvrsi
+func checkKeyArgs(name string, args [][]byte) interface{} {
+	var keys [][]byte
+	switch name {
+	case "GET", "MGET", "DEL", "EXISTS", "PFMERGE":
+		keys = args
+	case "SET", "BF.RESERVE", "BF.ADD", "BF.MADD", "BF.EXISTS", "PFADD", "PFCOUNT", "CMS.INITBYPROB", "CMS.INCRBY", "CMS.QUERY", "DOC.SET":
+		if len(args) > 0 {
+			keys = args[:1]
+		}
+	case "MSET":
+		for i := 0; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+	case "CMS.MERGE":
+		keys = append(keys, args[:1]...)
+		if len(args) > 2 {
+			keys = append(keys, args[2:]...)
+		}
+	}
+	for _, key := range keys {
+		if err := checkUserKey(string(key)); err != nil {
+			return respError("ERR " + err.Error())
+		}
+	}
+	return nil
+}
+
+func wrongArgs(name string) respError {
+	return respError("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+}
+
+//Opcije SET komande posle vrednosti: EX sekundi ili PX milisekundi, 0 znaci bez isteka
+func parseExpire(options [][]byte) (time.Duration, interface{}) {
+	if len(options) == 0 {
+		return 0, nil
+	}
+	if len(options) != 2 {
+		return 0, respError("ERR syntax error")
+	}
+	unit := time.Duration(0)
+	switch strings.ToUpper(string(options[0])) {
+	case "EX":
+		unit = time.Second
+	case "PX":
+		unit = time.Millisecond
+	default:
+		return 0, respError("ERR syntax error")
+	}
+	n, err := strconv.ParseInt(string(options[1]), 10, 64)
+	if err != nil {
+		return 0, respError("ERR value is not an integer or out of range")
+	}
+	if n <= 0 || n > int64(1<<62)/int64(unit) {
+		return 0, respError("ERR invalid expire time in 'set' command")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func (server *respServer) get(key string) []byte {
+	return server.sys.get("", key)
+}
+
+//Upis (put) brise prethodno vreme isteka kljuca, pa upis bez isteka ostaje bez njega, kao u Redis-u
+func (server *respServer) set(key string, value []byte, ttl time.Duration) bool {
+	if !server.sys.put("", key, value) {
+		return false
+	}
+	if ttl > 0 {
+		return server.sys.setExpire(key, time.Now().Add(ttl))
+	}
+	return true
+}
+
+//Jednom u sekundi brise kljuceve kojima je isteklo vreme, dok se stop ne zatvori
+func (server *respServer) expireLoop(stop chan bool) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			server.sys.mutex.Lock()
+			server.sys.expireDue()
+			server.sys.mutex.Unlock()
+		}
+	}
+}
+
+/*SCAN kursor [MATCH sablon] [COUNT n]
+Kursor 0 pocinje od prvog kljuca, a vraceni kursor sadrzi kljuc od kog se nastavlja (encodeCursor), pa server ne pamti
+otvorene prolaske i svaki kljuc koji postoji tokom celog prolaska vraca se tacno jednom. COUNT je broj pregledanih kljuceva (podrazumevano 10), pa odgovor
+sa MATCH moze imati i manje kljuceva. Kraj prolaska je kursor 0
+Iz SSTabela se cita samo deo opsega potreban za COUNT kljuceva, a ne svi zapisi od kursora do kraja*/
+func (server *respServer) scan(args [][]byte) interface{} {
+	if len(args) == 0 || len(args)%2 != 1 {
+		return wrongArgs("SCAN")
+	}
+	start, valid := decodeCursor(string(args[0]))
+	if !valid {
+		return respError("ERR invalid cursor")
+	}
+	pattern, count := "*", 10
+	for i := 1; i < len(args); i += 2 {
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			n, err := strconv.Atoi(string(args[i+1]))
+			if err != nil || n < 1 {
+				return respError("ERR value is not an integer or out of range")
+			}
+			count = n
+		default:
+			return respError("ERR syntax error")
+		}
+	}
+
+	kvs := server.sys.RangeScan("", start, "", count)
+	keys := [][]byte{}
+	for _, kv := range kvs {
+		if globMatch(pattern, kv.key) {
+			keys = append(keys, []byte(kv.key))
+		}
+	}
+	next := "0"
+	if len(kvs) == count {
+		//Najmanji kljuc veci od poslednjeg vracenog
+		next = encodeCursor(kvs[len(kvs)-1].key + "\x00")
+	}
+	return []interface{}{[]byte(next), keys}
+}
+
+/*Kursor je kljuc od kog se nastavlja, zapisan kao decimalan broj jer ga klijenti ocekuju: 1 pa po tri cifre (000-255)
+za svaki bajt kljuca. Duzina kursora raste sa kljucem, pa klijent mora da ga cuva kao niz cifara, a ne kao 64-bitni broj*/
+func encodeCursor(key string) string {
+	cursor := make([]byte, 1, 1+3*len(key))
+	cursor[0] = '1'
+	for i := 0; i < len(key); i++ {
+		cursor = append(cursor, '0'+key[i]/100, '0'+key[i]/10%10, '0'+key[i]%10)
+	}
+	return string(cursor)
+}
+
+//Vraca kljuc od kog se nastavlja i da li je kursor ispravan, kursor 0 pocinje od prvog kljuca
+func decodeCursor(cursor string) (string, bool) {
+	if cursor == "0" {
+		return "", true
+	}
+	if len(cursor)%3 != 1 || cursor[0] != '1' {
+		return "", false
+	}
+	key := make([]byte, 0, len(cursor)/3)
+	for i := 1; i < len(cursor); i += 3 {
+		n := 0
+		for _, digit := range []byte(cursor[i : i+3]) {
+			if digit < '0' || digit > '9' {
+				return "", false
+			}
+			n = n*10 + int(digit-'0')
+		}
+		if n > 255 {
+			return "", false
+		}
+		key = append(key, byte(n))
+	}
+	return string(key), true
+}
+
+/*Glob sablon kao u Redis-u: * je bilo koji niz znakova, ? jedan znak, [abc], [^a] i [a-z] skup znakova,
+\ oslobadja sledeci znak. Poredi bajtove*/
+func globMatch(pattern string, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			matched, rest := globClass(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			pattern = rest
+			s = s[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+//Proverava da li je c u skupu znakova koji pocinje posle [ i vraca ostatak sablona posle ]
+func globClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		if pattern[0] == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+		}
+		low, high := pattern[0], pattern[0]
+		if len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']' {
+			high = pattern[2]
+			pattern = pattern[2:]
+		}
+		if low > high {
+			low, high = high, low
+		}
+		if c >= low && c <= high {
+			matched = true
+		}
+		pattern = pattern[1:]
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:] //zatvorena ]
+	}
+	return matched != negate, pattern
+}
+
+//INFO [sekcija] - server, clients, stats ili keyspace, bez sekcije sve
+func (server *respServer) info(section string) []byte {
+	sections := []struct {
+		name   string
+		fields [][2]string
+	}{
+		{"server", [][2]string{
+			{"tcp_addr", server.addr},
+			{"uptime_in_seconds", strconv.FormatInt(int64(time.Since(server.started)/time.Second), 10)},
+			{"lsm_max_height", strconv.Itoa(server.sys.config.max_height)},
+		}},
+		{"clients", [][2]string{
+			{"connected_clients", strconv.FormatInt(atomic.LoadInt64(&server.clients), 10)},
+		}},
+		{"stats", [][2]string{
+			{"total_connections_received", strconv.FormatInt(atomic.LoadInt64(&server.connections), 10)},
+			{"total_commands_processed", strconv.FormatInt(atomic.LoadInt64(&server.commands), 10)},
+			{"expired_keys", strconv.FormatInt(server.sys.expired, 10)},
+			{"cache", strings.TrimPrefix(server.sys.cache.Statistics().String(), "cache: ")},
+			{"negative_cache", strings.TrimPrefix(server.sys.negative.String(), "negative cache: ")},
+			{"block_cache", strings.TrimPrefix(SSTable.BlockCacheStatistics().String(), "block cache: ")},
+		}},
+		{"keyspace", [][2]string{
+			{"memtable_records", strconv.Itoa(server.sys.memtable.curr_size)},
+			{"keys_with_expire", strconv.Itoa(len(server.sys.expireIndex()))},
+		}},
+	}
+	text := ""
+	for _, s := range sections {
+		if section != "" && section != "all" && section != s.name {
+			continue
+		}
+		if text != "" {
+			text += "\r\n"
+		}
+		text += "# " + strings.ToUpper(s.name[:1]) + s.name[1:] + "\r\n"
+		for _, field := range s.fields {
+			text += field[0] + ":" + field[1] + "\r\n"
+		}
+	}
+	return []byte(text)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//Pokrece RESP server nad sistemom u privremenom direktorijumu i vraca njegovu adresu
+func startRESPServer(t *testing.T) string {
+	sys := newTestSystem(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newRESPServer(sys, listener)
+	done := make(chan bool)
+	go func() {
+		server.serve()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		server.shutdown()
+		<-done
+	})
+	return server.addr
+}
+
+func dialTestRESP(t *testing.T, addr string) *respClient {
+	client, err := dialRESP(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+//Salje komandu i poredi odgovor sa ocekivanim
+func expectRESP(t *testing.T, client *respClient, expected interface{}, args ...string) {
+	t.Helper()
+	reply, err := client.do(args...)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	if !reflect.DeepEqual(reply, expected) {
+		t.Fatalf("%v: ocekivano %#v, dobijeno %#v", args, expected, reply)
+	}
+}
+
+func expectRESPError(t *testing.T, client *respClient, args ...string) {
+	t.Helper()
+	reply, err := client.do(args...)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	if _, failed := reply.(respError); !failed {
+		t.Fatalf("%v: ocekivana greska, dobijeno %#v", args, reply)
+	}
+}
+
+func TestRESPCommands(t *testing.T) {
+	client := dialTestRESP(t, startRESPServer(t))
+
+	expectRESP(t, client, respSimple("PONG"), "PING")
+	expectRESP(t, client, []byte("zdravo"), "PING", "zdravo")
+	expectRESP(t, client, respSimple("OK"), "SET", "a", "1")
+	expectRESP(t, client, []byte("1"), "GET", "a")
+	expectRESP(t, client, []byte(nil), "GET", "nema")
+	expectRESP(t, client, respSimple("OK"), "MSET", "b", "2", "c", "3")
+	expectRESP(t, client, []interface{}{[]byte("1"), []byte(nil), []byte("3")}, "MGET", "a", "nema", "c")
+	expectRESP(t, client, int64(2), "EXISTS", "a", "b", "nema")
+	expectRESP(t, client, int64(1), "DEL", "b", "nema")
+	expectRESP(t, client, []byte(nil), "GET", "b")
+
+	expectRESPError(t, client, "SET", reservedPrefix+"bucket/a", "1")
+	expectRESPError(t, client, "GET")
+	expectRESPError(t, client, "NEPOZNATA")
+	expectRESP(t, client, respSimple("OK"), "QUIT")
+}
+
+//SCAN kroz memtabelu i SSTabele vraca svaki postojeci kljuc tacno jednom i po redu
+func TestRESPScan(t *testing.T) {
+	client := dialTestRESP(t, startRESPServer(t))
+
+	expected := []string{}
+	for i := 0; i < 60; i++ {
+		key := "k" + strconv.Itoa(100+i)
+		expectRESP(t, client, respSimple("OK"), "SET", key, strconv.Itoa(i))
+		if i%4 == 0 {
+			expectRESP(t, client, int64(1), "DEL", key)
+		} else {
+			expected = append(expected, key)
+		}
+	}
+
+	//COUNT manji od broja zapisa u tabeli skracuje citanje tabela
+	for _, count := range []string{"2", "7", "100"} {
+		if keys := scanAll(t, client, count); !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("COUNT %s: ocekivano %v, dobijeno %v", count, expected, keys)
+		}
+	}
+
+	reply, err := client.do("SCAN", "0", "MATCH", "k11*", "COUNT", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matched := reply.([]interface{})[1].([]interface{})
+	if len(matched) != 8 {
+		t.Fatalf("ocekivano 8 kljuceva k11*, dobijeno %d", len(matched))
+	}
+}
+
+//Prolazi kroz sve kljuceve SCAN komandom sa zadatim COUNT
+func scanAll(t *testing.T, client *respClient, count string) []string {
+	keys := []string{}
+	cursor := "0"
+	for calls := 0; ; calls++ {
+		if calls > 1000 {
+			t.Fatal("SCAN se ne zavrsava")
+		}
+		reply, err := client.do("SCAN", cursor, "COUNT", count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		array, ok := reply.([]interface{})
+		if !ok || len(array) != 2 {
+			t.Fatalf("neispravan odgovor %#v", reply)
+		}
+		for _, key := range array[1].([]interface{}) {
+			keys = append(keys, string(key.([]byte)))
+		}
+		cursor = string(array[0].([]byte))
+		if cursor == "0" {
+			return keys
+		}
+	}
+}
+
+//Ugnjezdeni niz u komandi je greska protokola koja zatvara samo tu vezu
+func TestRESPRejectsNestedArray(t *testing.T) {
+	addr := startRESPServer(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go conn.Write([]byte(strings.Repeat("*1\r\n", 100000)))
+	reply, err := readRESP(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != respError(errRESPProtocol.Error()) {
+		t.Fatalf("ocekivana greska protokola, dobijeno %#v", reply)
+	}
+
+	expectRESP(t, dialTestRESP(t, addr), respSimple("PONG"), "PING")
+}
+
+func TestReadRESPCommand(t *testing.T) {
+	read := func(input string) ([][]byte, error) {
+		return readRESPCommand(bufio.NewReader(strings.NewReader(input)))
+	}
+	args, err := read("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")
+	if err != nil || !reflect.DeepEqual(args, [][]byte{[]byte("GET"), []byte("k")}) {
+		t.Fatalf("dobijeno %q, %v", args, err)
+	}
+	args, err = read("GET k\r\n")
+	if err != nil || !reflect.DeepEqual(args, [][]byte{[]byte("GET"), []byte("k")}) {
+		t.Fatalf("dobijeno %q, %v", args, err)
+	}
+	for _, input := range []string{"*1\r\n*1\r\n$1\r\nk\r\n", "*1\r\n:1\r\n", "*1\r\n$-1\r\n", "*-1\r\n", "*1\r\n$99999999\r\n"} {
+		if _, err := read(input); err != errRESPProtocol {
+			t.Errorf("%q: ocekivano %v, dobijeno %v", input, errRESPProtocol, err)
+		}
+	}
+}
+
+//Beskonacan red bez \n
+type endlessLine struct{}
+
+func (endlessLine) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+//Red se odbija cim predje respMaxBulk, bez citanja do kraja
+func TestReadRESPLineIsBounded(t *testing.T) {
+	if _, err := readRESPLine(bufio.NewReader(endlessLine{})); err != errRESPProtocol {
+		t.Fatalf("ocekivano %v, dobijeno %v", errRESPProtocol, err)
+	}
+}
+
+//Kursor sadrzi kljuc od kog se nastavlja, pa ga server ne mora pamtiti
+func TestSCANCursor(t *testing.T) {
+	for _, key := range []string{"", "a", "k100\x00", "\x00\xff\x7f"} {
+		decoded, valid := decodeCursor(encodeCursor(key))
+		if !valid || decoded != key {
+			t.Errorf("%q: dobijeno %q, %v", key, decoded, valid)
+		}
+	}
+	for _, cursor := range []string{"", "12", "1256", "2000", "1-01", "abc"} {
+		if _, valid := decodeCursor(cursor); valid {
+			t.Errorf("kursor %q je prihvacen", cursor)
+		}
+	}
+}
+
+/*Vreme isteka se upisuje kroz WAL, pa prezivljava pad bez urednog gasenja
+Upis kljuca iz drugog dela sistema (konzola, REST) brise njegovo vreme isteka*/
+func TestRESPExpireSurvivesCrash(t *testing.T) {
+	chdirTemp(t)
+	if err := InitWAL(); err != nil {
+		t.Fatal(err)
+	}
+	sys := newSystem(Default())
+	sys.initializeConfigs(true)
+	server := &respServer{sys: sys}
+	for _, args := range [][]string{{"a", "1", "EX", "100"}, {"b", "2", "PX", "1"}, {"c", "3", "EX", "100"}} {
+		command := make([][]byte, len(args))
+		for i, arg := range args {
+			command[i] = []byte(arg)
+		}
+		if reply := server.execute("SET", command); reply != respSimple("OK") {
+			t.Fatalf("SET %v: %#v", args, reply)
+		}
+	}
+	sys.put("", "c", []byte("4"))
+	//Pad: WAL je upisan, a memtabela se gubi
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !openSystem() {
+		t.Fatal("pokretanje nije uspelo")
+	}
+	t.Cleanup(func() { log.Close() })
+	expires := system.expireIndex()
+	if _, found := expires["a"]; !found {
+		t.Fatal("vreme isteka kljuca a je izgubljeno")
+	}
+	if _, found := expires["c"]; found {
+		t.Fatal("ponovo upisan kljuc c je zadrzao staro vreme isteka")
+	}
+	time.Sleep(2 * time.Millisecond)
+	for key, expected := range map[string]string{"a": "1", "b": "", "c": "4"} {
+		if value := string(system.get("", key)); value != expected {
+			t.Errorf("get(%s) = %q, ocekivano %q", key, value, expected)
+		}
+	}
+	if len(system.expireIndex()) != 1 || system.expired != 1 {
+		t.Fatalf("ocekivan jedan kljuc sa istekom i jedan istekao, dobijeno %d i %d", len(system.expireIndex()), system.expired)
+	}
+	if kvs := system.reservedScan(expirePrefix); len(kvs) != 1 || kvs[0].key != expirePrefix+"a" {
+		t.Fatalf("ocekivan samo zapis isteka kljuca a, dobijeno %v", kvs)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+)
+
+//Komande za vrednosti sa tipom (values.go), po uzoru na module Redis-a
+//Za razliku od Redis-a, BF.ADD ne pravi filter koji ne postoji, vec ga treba napraviti sa BF.RESERVE
+
+//BF.RESERVE kljuc stopa kapacitet | BF.ADD kljuc element | BF.MADD kljuc element... | BF.EXISTS kljuc element
+func (server *respServer) bloom(name string, args [][]byte) interface{} {
+	sys := server.sys
+	if len(args) < 2 {
+		return wrongArgs(name)
+	}
+	key := string(args[0])
+	server.sys.expire(key)
+	switch name {
+	case "BF.RESERVE":
+		if len(args) != 3 {
+			return wrongArgs(name)
+		}
+		rate, err := strconv.ParseFloat(string(args[1]), 64)
+		if err != nil {
+			return respError("ERR bad error rate")
+		}
+		capacity, err := strconv.Atoi(string(args[2]))
+		if err != nil {
+			return respError("ERR bad capacity")
+		}
+		if err = sys.BloomCreate("", key, capacity, rate); err != nil {
+			return valueError(err)
+		}
+		return respSimple("OK")
+	case "BF.ADD", "BF.MADD":
+		if name == "BF.ADD" && len(args) != 2 {
+			return wrongArgs(name)
+		}
+		added, err := sys.BloomAdd("", key, bulkStrings(args[1:])...)
+		if err != nil {
+			return valueError(err)
+		}
+		replies := make([]interface{}, len(added))
+		for i, first := range added {
+			replies[i] = respBool(first)
+		}
+		if name == "BF.ADD" {
+			return replies[0]
+		}
+		return replies
+	case "BF.EXISTS":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		found, err := sys.BloomContains("", key, string(args[1]))
+		if err != nil {
+			return valueError(err)
+		}
+		return respBool(found)
+	}
+	return respError("ERR unknown command '" + name + "'")
+}
+
+//Greska operacije nad vrednoscu sa tipom, vrednost drugog tipa je WRONGTYPE kao u Redis-u
+func valueError(err error) respError {
+	if errors.Is(err, ErrWrongType) {
+		return respError("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	return respError("ERR " + err.Error())
+}
+
+func respBool(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func bulkStrings(args [][]byte) []string {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = string(arg)
+	}
+	return strs
+}
+
+//PFADD kljuc element... | PFCOUNT kljuc | PFMERGE odrediste izvor...
+//Kao u Redis-u, PFADD pravi skicu koja ne postoji, PFCOUNT nepostojeceg kljuca je 0, a PFCOUNT prima samo jedan kljuc
+func (server *respServer) hll(name string, args [][]byte) interface{} {
+	sys := server.sys
+	if len(args) < 1 {
+		return wrongArgs(name)
+	}
+	key := string(args[0])
+	server.sys.expire(key)
+	switch name {
+	case "PFADD":
+		created := false
+		if _, err := sys.HLLEstimate("", key); err == ErrKeyNotFound {
+			if err = sys.HLLCreate("", key, defaultHLLPrecision); err != nil {
+				return valueError(err)
+			}
+			created = true
+		}
+		changed := false
+		if len(args) > 1 {
+			var err error
+			if changed, err = sys.HLLAdd("", key, bulkStrings(args[1:])...); err != nil {
+				return valueError(err)
+			}
+		}
+		return respBool(created || changed)
+	case "PFCOUNT":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		estimate, err := sys.HLLEstimate("", key)
+		if err == ErrKeyNotFound {
+			return 0
+		}
+		if err != nil {
+			return valueError(err)
+		}
+		return int64(estimate)
+	case "PFMERGE":
+		for _, source := range args[1:] {
+			server.sys.expire(string(source))
+		}
+		if err := sys.HLLMerge("", key, bulkStrings(args[1:])...); err != nil {
+			return valueError(err)
+		}
+		return respSimple("OK")
+	}
+	return respError("ERR unknown command '" + name + "'")
+}
+
+//CMS.INITBYPROB kljuc epsilon delta | CMS.INCRBY kljuc element broj [element broj]... | CMS.QUERY kljuc element...
+//CMS.MERGE odrediste broj_izvora izvor... - kao u Redis-u, bez WEIGHTS, a odrediste koje ne postoji pravi se od prvog izvora
+func (server *respServer) cms(name string, args [][]byte) interface{} {
+	sys := server.sys
+	if len(args) < 2 {
+		return wrongArgs(name)
+	}
+	key := string(args[0])
+	server.sys.expire(key)
+	switch name {
+	case "CMS.INITBYPROB":
+		if len(args) != 3 {
+			return wrongArgs(name)
+		}
+		epsilon, err1 := strconv.ParseFloat(string(args[1]), 64)
+		delta, err2 := strconv.ParseFloat(string(args[2]), 64)
+		if err1 != nil || err2 != nil {
+			return respError("ERR invalid epsilon or delta")
+		}
+		if err := sys.CMSCreate("", key, epsilon, delta); err != nil {
+			return valueError(err)
+		}
+		return respSimple("OK")
+	case "CMS.INCRBY":
+		if len(args)%2 != 1 {
+			return wrongArgs(name)
+		}
+		counts := make([]uint64, 0, len(args)/2)
+		for i := 2; i < len(args); i += 2 {
+			count, err := strconv.ParseUint(string(args[i]), 10, 64)
+			if err != nil {
+				return respError("ERR cannot parse number")
+			}
+			counts = append(counts, count)
+		}
+		replies := make([]interface{}, len(counts))
+		for i, count := range counts {
+			estimate, err := sys.CMSAdd("", key, string(args[1+2*i]), count)
+			if err != nil {
+				return valueError(err)
+			}
+			replies[i] = int64(estimate)
+		}
+		return replies
+	case "CMS.QUERY":
+		replies := make([]interface{}, len(args)-1)
+		for i, item := range args[1:] {
+			estimate, err := sys.CMSEstimate("", key, string(item))
+			if err != nil {
+				return valueError(err)
+			}
+			replies[i] = int64(estimate)
+		}
+		return replies
+	case "CMS.MERGE":
+		count, err := strconv.Atoi(string(args[1]))
+		if err != nil || count < 1 || count != len(args)-2 {
+			return respError("ERR invalid number of keys")
+		}
+		for _, source := range args[2:] {
+			server.sys.expire(string(source))
+		}
+		if err := sys.CMSMerge("", key, bulkStrings(args[2:])...); err != nil {
+			return valueError(err)
+		}
+		return respSimple("OK")
+	}
+	return respError("ERR unknown command '" + name + "'")
+}
+
+//DOC.SET kljuc tekst | DOC.SIMILAR udaljenost tekst - niz parova [kljuc, udaljenost], od najblizeg
+func (server *respServer) document(name string, args [][]byte) interface{} {
+	if len(args) != 2 {
+		return wrongArgs(name)
+	}
+	switch name {
+	case "DOC.SET":
+		key := string(args[0])
+		if err := server.sys.PutDocument("", key, string(args[1])); err != nil {
+			return valueError(err)
+		}
+		return respSimple("OK")
+	case "DOC.SIMILAR":
+		distance, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			return respError("ERR value is not an integer or out of range")
+		}
+		matches, err := server.sys.NearDuplicates("", string(args[1]), distance)
+		if err != nil {
+			return valueError(err)
+		}
+		replies := make([]interface{}, len(matches))
+		for i, match := range matches {
+			replies[i] = []interface{}{match.Key, match.Distance}
+		}
+		return replies
+	}
+	return respError("ERR unknown command '" + name + "'")
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"kv/SSTable"
+	"kv/kompakcije"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Istorija komandi interaktivnog rezima, cuva se izmedju pokretanja
+const historyPath = "data/history.txt"
+
+//Najvise komandi koje se pamte u istoriji
+const historySize = 1000
+
+/*Interaktivna konzola nad sistemom
+Ako je zadat korisnik, svaka operacija nad podacima trosi njegov token (token bucket)*/
+type shell struct {
+	sys     *System
+	user    string
+	history []string
+}
+
+var errShellExit = errors.New("exit")
+
+//Komanda shell [--user korisnik] [--script fajl] [--diff-socket putanja] - interaktivna konzola, ili izvrsavanje komandi iz fajla
+//U rezimu skripte prazni redovi i redovi koji pocinju sa # se preskacu, a prva neuspesna komanda prekida izvrsavanje
+//Vraca izlazni kod procesa: 0 = sve komande su uspele, 1 = neka komanda nije uspela, 2 = neispravni argumenti
+func runShell(sys *System, args []string) int {
+	flags := flag.NewFlagSet("shell", flag.ContinueOnError)
+	user := flags.String("user", "", "korisnik ciji se tokeni trose")
+	script := flags.String("script", "", "fajl sa komandama, - za standardni ulaz")
+	diffSocket := flags.String("diff-socket", "", "lokalni socket na kome se izlaze baza za diff")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Println("upotreba: shell [--user korisnik] [--script fajl] [--diff-socket putanja]")
+		return 2
+	}
+	stopDiff, err := sys.serveDiff(*diffSocket)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	defer stopDiff()
+	s := &shell{sys: sys, user: *user}
+	if *script != "" {
+		return s.runScript(*script)
+	}
+	s.loadHistory()
+	s.interactive(os.Stdin)
+	return 0
+}
+
+func (s *shell) interactive(input io.Reader) {
+	fmt.Println("help za spisak komandi, exit za izlaz")
+	scanner := bufio.NewScanner(input)
+	for {
+		fmt.Print("kv> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		//!! ponavlja poslednju komandu, !n n-tu komandu iz istorije
+		if strings.HasPrefix(line, "!") {
+			repeated, err := s.fromHistory(line[1:])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			line = repeated
+			fmt.Println(line)
+		}
+		s.remember(line)
+		err := s.execute(line)
+		if err == errShellExit {
+			return
+		}
+		if err != nil {
+			fmt.Println("greska:", err)
+		}
+	}
+}
+
+func (s *shell) runScript(path string) int {
+	input := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Println(err)
+			return 2
+		}
+		defer file.Close()
+		input = file
+	}
+	scanner := bufio.NewScanner(input)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Println("> "+line)
+		err := s.execute(line)
+		if err == errShellExit {
+			return 0
+		}
+		if err != nil {
+			fmt.Printf("greska u redu %d: %s\n", number, err)
+			return 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
+
+//Izvrsava jednu komandu dok drzi bravu sistema, pa se konfiguracija ne menja usred komande
+func (s *shell) execute(line string) error {
+	fields := strings.Fields(line)
+	command, args := strings.ToLower(fields[0]), fields[1:]
+	switch command {
+	case "exit", "quit":
+		return errShellExit
+	case "help":
+		s.help()
+		return nil
+	case "history":
+		for i, entry := range s.history {
+			fmt.Printf("%4d  %s\n", i+1, entry)
+		}
+		return nil
+	case "config":
+		if len(args) == 1 && args[0] == "reload" {
+			return s.reload()
+		}
+	}
+
+	s.sys.mutex.Lock()
+	defer s.sys.mutex.Unlock()
+	switch command {
+	case "put":
+		//Vrednost je ostatak reda, pa moze sadrzati razmake
+		if len(args) < 2 {
+			return errors.New("upotreba: put <kljuc> <vrednost>")
+		}
+		if err := checkUserKey(args[0]); err != nil {
+			return err
+		}
+		rest := strings.TrimSpace(line[len(fields[0]):])
+		value := strings.TrimSpace(rest[len(args[0]):])
+		if err := s.charge(); err != nil {
+			return err
+		}
+		if !s.sys.put("", args[0], []byte(value)) {
+			return errors.New("upis nije uspeo")
+		}
+		fmt.Println("OK")
+	case "get":
+		if len(args) != 1 {
+			return errors.New("upotreba: get <kljuc>")
+		}
+		if err := checkUserKey(args[0]); err != nil {
+			return err
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		value := s.sys.get("", args[0])
+		if value == nil {
+			fmt.Println("(nema)")
+		} else {
+			fmt.Println(string(value))
+		}
+	case "delete", "del":
+		if len(args) != 1 {
+			return errors.New("upotreba: delete <kljuc>")
+		}
+		if err := checkUserKey(args[0]); err != nil {
+			return err
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		if s.sys.Delete("", args[0]) {
+			fmt.Println("obrisano")
+		} else {
+			fmt.Println("(nema)")
+		}
+	case "scan":
+		//scan [pocetak [kraj [limit]]], - je otvorena granica
+		if len(args) > 3 {
+			return errors.New("upotreba: scan [pocetak|- [kraj|- [limit]]]")
+		}
+		bounds := []string{"", ""}
+		for i := 0; i < len(args) && i < 2; i++ {
+			if args[i] != "-" {
+				bounds[i] = args[i]
+			}
+		}
+		limit := 0
+		if len(args) == 3 {
+			correct, val := CheckValInt(args[2], 1, 1000000)
+			if !correct {
+				return errors.New("limit mora biti pozitivan ceo broj")
+			}
+			limit = val
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		s.printKVs(s.sys.RangeScan("", bounds[0], bounds[1], limit))
+	case "prefix":
+		if len(args) != 1 {
+			return errors.New("upotreba: prefix <prefiks>")
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		s.printKVs(s.sys.PrefixScan("", args[0]))
+	case "flush":
+		if err := s.sys.Flush(); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	case "compact":
+		if err := kompakcije.Kompakcija(s.sys.config.compaction_size, s.sys.config.max_height, s.sys.config.bloom_precision); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	case "bloom":
+		return s.bloom(args)
+	case "hll":
+		return s.hll(args)
+	case "cms":
+		return s.cms(args)
+	case "doc":
+		return s.doc(line, args)
+	case "stats":
+		s.stats()
+	case "hotkeys":
+		n := 10
+		if len(args) == 1 {
+			correct, val := CheckValInt(args[0], 1, 10000)
+			if !correct {
+				return errors.New("upotreba: hotkeys [broj kljuceva]")
+			}
+			n = val
+		}
+		printHotKeys(n)
+	case "config":
+		if len(args) != 0 {
+			return errors.New("upotreba: config [reload]")
+		}
+		s.sys.config.PrintConfig()
+	default:
+		return fmt.Errorf("nepoznata komanda %s (help za spisak)", command)
+	}
+	return nil
+}
+
+//config reload se izvrsava van brave sistema, jer je ReloadConfig sam uzima
+func (s *shell) reload() error {
+	result, err := s.sys.ReloadConfig(configPath())
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+//Trosi token korisnika konzole, ako je zadat
+func (s *shell) charge() error {
+	if s.user != "" && !CheckTokenBucket(s.user) {
+		return fmt.Errorf("korisnik %s nema vise tokena", s.user)
+	}
+	return nil
+}
+
+func (s *shell) printKVs(kvs []KV) {
+	for _, kv := range kvs {
+		fmt.Printf("%s=%s\n", kv.key, kv.value)
+	}
+	fmt.Printf("(%d)\n", len(kvs))
+}
+
+func (s *shell) stats() {
+	fmt.Printf("memtable: %d/%d zapisa\n", s.sys.memtable.curr_size, s.sys.memtable.max_size)
+	for level := 1; level <= s.sys.config.max_height; level++ {
+		fmt.Printf("nivo %d: %d tabela\n", level, SSTable.FindLastFile(level)-1)
+	}
+	fmt.Println(s.sys.cache.Statistics().String())
+	fmt.Println(s.sys.negative.String())
+	fmt.Println(SSTable.BlockCacheStatistics().String())
+}
+
+func (s *shell) help() {
+	fmt.Print(`put <kljuc> <vrednost>          upis (vrednost je ostatak reda)
+get <kljuc>                     citanje
+delete <kljuc>                  brisanje
+scan [pocetak [kraj [limit]]]   kljucevi iz opsega [pocetak, kraj), - je otvorena granica
+prefix <prefiks>                kljucevi sa prefiksom
+flush                           upis memtabele u SSTabelu
+compact                         kompakcija
+bloom create <kljuc> <n> <p>    bloom filter za n elemenata sa stopom laznih pozitiva p
+bloom add <kljuc> <element>...  dodavanje u bloom filter
+bloom check <kljuc> <element>   provera elementa u bloom filteru
+hll create <kljuc> <p>          HyperLogLog skica preciznosti p (4-16 bitova)
+hll add <kljuc> <element>...    dodavanje u skicu
+hll count <kljuc>               procena broja razlicitih elemenata
+hll merge <kljuc> <izvor>...    spajanje skica u skicu pod kljucem
+cms create <kljuc> <e> <d>      Count-Min skica sa greskom e*ukupno uz verovatnocu 1-d
+cms create <kljuc> like <izvor> prazna skica koja se moze sabrati sa skicom izvora
+cms add <kljuc> <element> [n]   dodavanje n (1) pojavljivanja elementa
+cms query <kljuc> <element>     procena broja pojavljivanja
+cms merge <kljuc> <izvor>...    sabiranje skica u skicu pod kljucem
+doc put <kljuc> <tekst>         upis dokumenta i njegovog SimHash otiska
+doc similar <d> <tekst>         dokumenti na udaljenosti najvise d od teksta
+stats                           statistika memtabele, nivoa i kesova
+hotkeys [n]                     najcitaniji i najcesce upisivani kljucevi
+config [reload]                 ispis konfiguracije, ili ponovno ucitavanje fajla
+history                         prethodne komande, !n ponavlja n-tu, !! poslednju
+exit                            izlaz
+`)
+}
+
+//Dodaje komandu u istoriju i u fajl istorije
+func (s *shell) remember(line string) {
+	s.history = append(s.history, line)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	file, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+func (s *shell) loadHistory() {
+	file, err := os.Open(historyPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		s.history = append(s.history, scanner.Text())
+	}
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+}
+
+func (s *shell) fromHistory(reference string) (string, error) {
+	if len(s.history) == 0 {
+		return "", errors.New("istorija je prazna")
+	}
+	if reference == "!" {
+		return s.history[len(s.history)-1], nil
+	}
+	n, err := strconv.Atoi(reference)
+	if err != nil || n < 1 || n > len(s.history) {
+		return "", fmt.Errorf("nema komande %s u istoriji", reference)
+	}
+	return s.history[n-1], nil
+}
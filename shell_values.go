@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Komande konzole za vrednosti sa tipom (values.go), prvi argument je podkomanda, a drugi kljuc
+
+func (s *shell) bloom(args []string) error {
+	usage := errors.New("upotreba: bloom create <kljuc> <broj elemenata> <stopa> | bloom add <kljuc> <element>... | bloom check <kljuc> <element>")
+	if len(args) < 3 {
+		return usage
+	}
+	if err := checkUserKey(args[1]); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "create":
+		if len(args) != 4 {
+			return usage
+		}
+		expected, err := strconv.Atoi(args[2])
+		if err != nil {
+			return errors.New("broj elemenata mora biti ceo broj")
+		}
+		rate, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return errors.New("stopa laznih pozitiva mora biti broj")
+		}
+		if err = s.charge(); err != nil {
+			return err
+		}
+		if err = s.sys.BloomCreate("", args[1], expected, rate); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	case "add":
+		if err := s.charge(); err != nil {
+			return err
+		}
+		added, err := s.sys.BloomAdd("", args[1], args[2:]...)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for _, first := range added {
+			if first {
+				count++
+			}
+		}
+		fmt.Printf("dodato %d, novih %d\n", len(added), count)
+	case "check":
+		if len(args) != 3 {
+			return usage
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		found, err := s.sys.BloomContains("", args[1], args[2])
+		if err != nil {
+			return err
+		}
+		if found {
+			fmt.Println("mozda postoji")
+		} else {
+			fmt.Println("ne postoji")
+		}
+	default:
+		return usage
+	}
+	return nil
+}
+
+func (s *shell) hll(args []string) error {
+	usage := errors.New("upotreba: hll create <kljuc> <preciznost> | hll add <kljuc> <element>... | hll count <kljuc> | hll merge <kljuc> <izvor>...")
+	if len(args) < 2 {
+		return usage
+	}
+	if err := checkUserKey(args[1]); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return usage
+		}
+		precision, err := strconv.ParseUint(args[2], 10, 8)
+		if err != nil {
+			return errors.New("preciznost mora biti ceo broj od 4 do 16")
+		}
+		if err = s.charge(); err != nil {
+			return err
+		}
+		if err = s.sys.HLLCreate("", args[1], uint8(precision)); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	case "add":
+		if len(args) < 3 {
+			return usage
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		changed, err := s.sys.HLLAdd("", args[1], args[2:]...)
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Println("OK")
+		} else {
+			fmt.Println("OK (skica nije promenjena)")
+		}
+	case "count":
+		if len(args) != 2 {
+			return usage
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		estimate, err := s.sys.HLLEstimate("", args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(estimate)
+	case "merge":
+		if len(args) < 3 {
+			return usage
+		}
+		for _, source := range args[2:] {
+			if err := checkUserKey(source); err != nil {
+				return err
+			}
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		if err := s.sys.HLLMerge("", args[1], args[2:]...); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	default:
+		return usage
+	}
+	return nil
+}
+
+func (s *shell) cms(args []string) error {
+	usage := errors.New("upotreba: cms create <kljuc> <epsilon> <delta> | cms create <kljuc> like <izvor> | cms add <kljuc> <element> [broj] | cms query <kljuc> <element> | cms merge <kljuc> <izvor>...")
+	if len(args) < 3 {
+		return usage
+	}
+	if err := checkUserKey(args[1]); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "create":
+		if len(args) != 4 {
+			return usage
+		}
+		if args[2] == "like" {
+			if err := checkUserKey(args[3]); err != nil {
+				return err
+			}
+			if err := s.charge(); err != nil {
+				return err
+			}
+			if err := s.sys.CMSCreateLike("", args[1], args[3]); err != nil {
+				return err
+			}
+			fmt.Println("OK")
+			return nil
+		}
+		epsilon, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return errors.New("epsilon mora biti broj")
+		}
+		delta, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return errors.New("delta mora biti broj")
+		}
+		if err = s.charge(); err != nil {
+			return err
+		}
+		if err = s.sys.CMSCreate("", args[1], epsilon, delta); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	case "add":
+		if len(args) > 4 {
+			return usage
+		}
+		count := uint64(1)
+		if len(args) == 4 {
+			var err error
+			if count, err = strconv.ParseUint(args[3], 10, 64); err != nil {
+				return errors.New("broj pojavljivanja mora biti nenegativan ceo broj")
+			}
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		estimate, err := s.sys.CMSAdd("", args[1], args[2], count)
+		if err != nil {
+			return err
+		}
+		fmt.Println(estimate)
+	case "query":
+		if len(args) != 3 {
+			return usage
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		estimate, err := s.sys.CMSEstimate("", args[1], args[2])
+		if err != nil {
+			return err
+		}
+		fmt.Println(estimate)
+	case "merge":
+		for _, source := range args[2:] {
+			if err := checkUserKey(source); err != nil {
+				return err
+			}
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		if err := s.sys.CMSMerge("", args[1], args[2:]...); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	default:
+		return usage
+	}
+	return nil
+}
+
+//Tekst dokumenta je ostatak reda, kao vrednost u put
+func (s *shell) doc(line string, args []string) error {
+	usage := errors.New("upotreba: doc put <kljuc> <tekst> | doc similar <udaljenost> <tekst>")
+	if len(args) < 3 {
+		return usage
+	}
+	rest := strings.TrimSpace(line[len(strings.Fields(line)[0]):])
+	rest = strings.TrimSpace(rest[len(args[0]):])
+	text := strings.TrimSpace(rest[len(args[1]):])
+	switch args[0] {
+	case "put":
+		if err := checkUserKey(args[1]); err != nil {
+			return err
+		}
+		if err := s.charge(); err != nil {
+			return err
+		}
+		if err := s.sys.PutDocument("", args[1], text); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+	case "similar":
+		distance, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.New("udaljenost mora biti ceo broj")
+		}
+		if err = s.charge(); err != nil {
+			return err
+		}
+		matches, err := s.sys.NearDuplicates("", text, distance)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			fmt.Printf("%s (udaljenost %d)\n", match.Key, match.Distance)
+		}
+		fmt.Printf("(%d)\n", len(matches))
+	default:
+		return usage
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package simhash
+
+import "sort"
+
+//Indeks otisaka za pretragu po Hamming udaljenosti do maxDistance (permutovane tabele, Manku i dr.)
+//Otisak se deli na maxDistance+1 blokova bitova, pa dva otiska na udaljenosti najvise maxDistance
+//imaju bar jedan isti blok. Za svaki blok postoji tabela u kojoj je taj blok kljuc, pa se porede
+//samo otisci koji se poklapaju u nekom bloku umesto svih
+type Index struct {
+	maxDistance  int
+	masks        []uint64
+	tables       []map[uint64][]string
+	fingerprints map[string]uint64
+}
+
+//Kljuc i udaljenost njegovog otiska od trazenog
+type Match struct {
+	Key      string
+	Distance int
+}
+
+func NewIndex(maxDistance int) *Index {
+	if maxDistance < 0 {
+		maxDistance = 0
+	}
+	if maxDistance > 63 {
+		maxDistance = 63
+	}
+	blocks := maxDistance + 1
+	index := &Index{
+		maxDistance:  maxDistance,
+		masks:        make([]uint64, blocks),
+		tables:       make([]map[uint64][]string, blocks),
+		fingerprints: make(map[string]uint64),
+	}
+	start := 0
+	for i := 0; i < blocks; i++ {
+		end := (i + 1) * 64 / blocks
+		for bit := start; bit < end; bit++ {
+			index.masks[i] |= 1 << uint(bit)
+		}
+		index.tables[i] = make(map[uint64][]string)
+		start = end
+	}
+	return index
+}
+
+func (index *Index) MaxDistance() int {
+	return index.maxDistance
+}
+
+func (index *Index) Len() int {
+	return len(index.fingerprints)
+}
+
+func (index *Index) Contains(key string) bool {
+	_, found := index.fingerprints[key]
+	return found
+}
+
+//Dodaje ili menja otisak kljuca
+func (index *Index) Add(key string, fingerprint uint64) {
+	index.Remove(key)
+	index.fingerprints[key] = fingerprint
+	for i, mask := range index.masks {
+		index.tables[i][fingerprint&mask] = append(index.tables[i][fingerprint&mask], key)
+	}
+}
+
+func (index *Index) Remove(key string) {
+	fingerprint, found := index.fingerprints[key]
+	if !found {
+		return
+	}
+	delete(index.fingerprints, key)
+	for i, mask := range index.masks {
+		keys := index.tables[i][fingerprint&mask]
+		for j, k := range keys {
+			if k == key {
+				keys = append(keys[:j], keys[j+1:]...)
+				break
+			}
+		}
+		if len(keys) == 0 {
+			delete(index.tables[i], fingerprint&mask)
+		} else {
+			index.tables[i][fingerprint&mask] = keys
+		}
+	}
+}
+
+//Vraca kljuceve ciji su otisci na udaljenosti najvise distance, sortirane po udaljenosti pa po kljucu
+//Za distance vece od maxDistance indeksa porede se svi otisci
+func (index *Index) Query(fingerprint uint64, distance int) []Match {
+	matches := make([]Match, 0)
+	if distance > index.maxDistance {
+		for key, other := range index.fingerprints {
+			if d := Distance(fingerprint, other); d <= distance {
+				matches = append(matches, Match{Key: key, Distance: d})
+			}
+		}
+	} else {
+		seen := make(map[string]bool)
+		for i, mask := range index.masks {
+			for _, key := range index.tables[i][fingerprint&mask] {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if d := Distance(fingerprint, index.fingerprints[key]); d <= distance {
+					matches = append(matches, Match{Key: key, Distance: d})
+				}
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Key < matches[j].Key
+	})
+	return matches
+}
@@ -0,0 +1,95 @@
+package simhash
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+//Menja tacno n nasumicnih bitova otiska
+func flip(random *rand.Rand, fingerprint uint64, n int) uint64 {
+	for _, bit := range random.Perm(64)[:n] {
+		fingerprint ^= 1 << uint(bit)
+	}
+	return fingerprint
+}
+
+//Poredjenje sa svim otiscima (Query za udaljenost vecu od maxDistance), za proveru indeksa
+func bruteForce(index *Index, fingerprint uint64, distance int) []Match {
+	matches := make([]Match, 0)
+	for _, match := range index.Query(fingerprint, 64) {
+		if match.Distance <= distance {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+//Svaka kopija na udaljenosti do maxDistance se nalazi preko nekog bloka, a dalji otisci se ne vracaju
+func TestIndexFindsNearDuplicates(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, maxDistance := range []int{0, 3, 6, 10} {
+		index := NewIndex(maxDistance)
+		originals := make([]uint64, 200)
+		for i := range originals {
+			originals[i] = random.Uint64()
+			index.Add("doc"+strconv.Itoa(i), originals[i])
+		}
+		for i, original := range originals {
+			for distance := 0; distance <= maxDistance; distance++ {
+				near := flip(random, original, distance)
+				index.Add("near", near)
+				matches := index.Query(original, maxDistance)
+				found := false
+				for _, match := range matches {
+					if match.Distance > maxDistance {
+						t.Fatalf("maxDistance %d: vracen %s na udaljenosti %d", maxDistance, match.Key, match.Distance)
+					}
+					found = found || match.Key == "near" && match.Distance == distance
+				}
+				if !found {
+					t.Fatalf("maxDistance %d: kopija doc%d na udaljenosti %d nije nadjena", maxDistance, i, distance)
+				}
+				if expected := bruteForce(index, original, maxDistance); !reflect.DeepEqual(matches, expected) {
+					t.Fatalf("maxDistance %d: ocekivano %v, dobijeno %v", maxDistance, expected, matches)
+				}
+			}
+			far := flip(random, original, maxDistance+1+random.Intn(64-maxDistance))
+			index.Add("near", far)
+			for _, match := range index.Query(original, maxDistance) {
+				if match.Key == "near" {
+					t.Fatalf("maxDistance %d: vracen otisak na udaljenosti %d", maxDistance, match.Distance)
+				}
+			}
+		}
+		index.Remove("near")
+		if index.Len() != len(originals) || index.Contains("near") {
+			t.Fatalf("ocekivano %d otisaka posle brisanja, dobijeno %d", len(originals), index.Len())
+		}
+	}
+}
+
+//Tekst sa jednom izmenjenom recju je blizu originala, a potpuno drugaciji tekst nije
+func TestIndexText(t *testing.T) {
+	words := make([]string, 200)
+	for i := range words {
+		words[i] = "rec" + strconv.Itoa(i)
+	}
+	original := strings.Join(words, " ")
+	words[50] = "izmenjena"
+	edited := strings.Join(words, " ")
+	other := make([]string, 200)
+	for i := range other {
+		other[i] = "drugo" + strconv.Itoa(i)
+	}
+
+	index := NewIndex(6)
+	index.Add("original", FromText(original))
+	index.Add("drugi", FromText(strings.Join(other, " ")))
+	matches := index.Query(FromText(edited), 6)
+	if len(matches) != 1 || matches[0].Key != "original" {
+		t.Fatalf("ocekivan samo original, dobijeno %v", matches)
+	}
+}
@@ -0,0 +1,52 @@
+package simhash
+
+import (
+	"github.com/spaolacci/murmur3"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+//64-bitni SimHash otisak teksta - slicni tekstovi imaju otiske na maloj Hamming udaljenosti
+//Svaki token glasa za svaki bit svog hash-a sa svojom tezinom, a bit otiska je 1 ako je zbir glasova pozitivan
+func Compute(weights map[string]int) uint64 {
+	var votes [64]int
+	for token, weight := range weights {
+		h := murmur3.Sum64([]byte(token))
+		for i := 0; i < 64; i++ {
+			if h&(1<<uint(i)) != 0 {
+				votes[i] += weight
+			} else {
+				votes[i] -= weight
+			}
+		}
+	}
+	fingerprint := uint64(0)
+	for i, vote := range votes {
+		if vote > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+//Deli tekst na reci (slova i cifre, mala slova), tezina reci je broj njenih pojavljivanja
+func Tokenize(text string) map[string]int {
+	weights := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		weights[word]++
+	}
+	return weights
+}
+
+func FromText(text string) uint64 {
+	return Compute(Tokenize(text))
+}
+
+//Hamming udaljenost dva otiska
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -145,7 +145,6 @@ func (s *SkipList) AddElement(input []byte) bool {
 		return false
 
 	}
-	return false
 }
 
 /*Funkcija brise element iz skip liste - postavlja tombstone podatka na 1
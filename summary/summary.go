@@ -2,10 +2,22 @@ package summary
 
 import (
 	"encoding/binary"
+	"errors"
+	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 )
 
+//Summary ucitan u memoriju - kljucevi su sortirani kao u SSTabeli
+type Summary struct {
+	keys    []string
+	offsets []uint64
+	size    int
+}
+
+var ErrCorrupted = errors.New("summary corrupted")
+
 func NewSummary(keys []string, keyLen []uint64, name string) {
 	file, err := os.OpenFile("data/SSTable"+name+"/summary"+name+".txt", os.O_WRONLY, 0666)
 	if err != nil {
@@ -32,29 +44,49 @@ func NewSummary(keys []string, keyLen []uint64, name string) {
 	}
 }
 
-func Find(key string, name string) (uint64, bool) {
-	file, err := os.Open("data/SSTable" + name + "/summary" + name + ".txt")
-	defer file.Close()
+//Ucitava ceo summary fajl u memoriju, kako bi se pretraga radila bez citanja sa diska
+func LoadSummary(name string) (*Summary, error) {
+	bytes, err := ioutil.ReadFile("data/SSTable" + name + "/summary" + name + ".txt")
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	offset := uint64(0)
-	found := false
-	for {
-		bytes := make([]byte, 8)
-		_, err = file.Read(bytes)
-		if err != nil {
-			break //EOF
+	summary := &Summary{keys: make([]string, 0), offsets: make([]uint64, 0), size: len(bytes)}
+	for i := 0; i < len(bytes); {
+		if i+8 > len(bytes) {
+			return nil, ErrCorrupted
 		}
-		keyLen := binary.LittleEndian.Uint64(bytes)
-		readKey := make([]byte, keyLen)
-		file.Read(readKey)
-		file.Read(bytes)
-		if string(readKey) == key {
-			offset = binary.LittleEndian.Uint64(bytes)
-			found = true
-			break
+		keyLen := binary.LittleEndian.Uint64(bytes[i : i+8])
+		i += 8
+		if keyLen > uint64(len(bytes)-i) || i+int(keyLen)+8 > len(bytes) {
+			return nil, ErrCorrupted
 		}
+		summary.keys = append(summary.keys, string(bytes[i:i+int(keyLen)]))
+		i += int(keyLen)
+		summary.offsets = append(summary.offsets, binary.LittleEndian.Uint64(bytes[i:i+8]))
+		i += 8
 	}
-	return uint64(offset), found
+	return summary, nil
+}
+
+//Binarna pretraga ucitanog summary-ja, vraca offset u indexu
+func (summary *Summary) Find(key string) (uint64, bool) {
+	i := sort.SearchStrings(summary.keys, key)
+	if i < len(summary.keys) && summary.keys[i] == key {
+		return summary.offsets[i], true
+	}
+	return 0, false
+}
+
+//Vraca offset u indexu prvog kljuca veceg ili jednakog zadatom, false ako takvog nema
+func (summary *Summary) Seek(key string) (uint64, bool) {
+	i := sort.SearchStrings(summary.keys, key)
+	if i < len(summary.keys) {
+		return summary.offsets[i], true
+	}
+	return 0, false
+}
+
+//Priblizna velicina summary-ja u memoriji u bajtovima
+func (summary *Summary) Size() int {
+	return summary.size
 }
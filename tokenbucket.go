@@ -1,11 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"encoding/binary"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 )
 
@@ -32,73 +29,55 @@ func formBytes(time uint64, tokens uint32) []byte {
 	return bytes
 }
 
-// formira niz bajtova sa inicijalnim vrednostima
-func formInitialBytes() []byte {
-	return formBytes(now(), tokensPerReset-1)
+// InitializeTokenBucketConfigs - funkcija koja inicijalizuje konfiguracije potrebne za funkcionisanje token bucket-a
+func InitializeTokenBucketConfigs(tokensPerReset_ uint32, minutesBeforeReset_ uint32) {
+	tokensPerReset = tokensPerReset_
+	minutesBeforeReset = minutesBeforeReset_
 }
 
-// LoadConfig - ucitava podesavanja iz eksterne datoteke
-func LoadConfig() error {
-	config, err := os.Open("config.txt")
-	if err != nil {
-		return err
-	}
+// baket korisnika se cuva u rezervisanom delu prostora kljuceva, pa ga korisnicki upis ne moze prepisati
+const bucketPrefix = reservedPrefix + "bucket/"
 
-	scanner := bufio.NewScanner(config)
+func bucketKey(user string) string {
+	return bucketPrefix + user
+}
 
-	scanner.Scan() // preskacemo linije nevezane za token bucket
-	scanner.Scan()
-	scanner.Scan()
+// CheckTokenBucket - funkcija koja implementira token bucket algoritam
+func CheckTokenBucket(user string) bool {
+	return TakeTokens(user, 1)
+}
 
-	scanner.Scan()
-	tokens, err := strconv.Atoi(scanner.Text()[7:])
-	if err != nil {
-		return err
+// TakeTokens - uzima n tokena korisnika odjednom, ako ih nema dovoljno ne uzima nijedan i odbija zahtev
+func TakeTokens(user string, n uint32) bool {
+	timestamp, tokens := readBucket(user)
+	if tokens < n { // nema dovoljno tokena, zahtev odbijen
+		fmt.Println("Previse zahteva u ovom periodu vremena, zahtev odbijen. Molim Vas sacekajte.")
+		return false
 	}
-	tokensPerReset = uint32(tokens)
+	system.write(bucketKey(user), formBytes(timestamp, tokens-n))
+	return true
+}
 
-	scanner.Scan()
-	minutes, err := strconv.Atoi(scanner.Text()[8:])
-	if err != nil {
-		return err
+// vraca vreme poslednjeg reseta i broj preostalih tokena korisnika
+// korisnik koji prvi put pravi zahtev, ciji je interval prosao ili ciji je zapis neispravan dobija pun baket
+func readBucket(user string) (uint64, uint32) {
+	val := system.read(bucketKey(user))
+	if len(val) < 12 {
+		return now(), tokensPerReset
 	}
-	minutesBeforeReset = uint32(minutes)
-
-	err = config.Close()
-	if err != nil {
-		return err
+	timestamp := binary.LittleEndian.Uint64(val[:8])       // vreme proslog reseta
+	if isPast(timestamp + uint64(minutesBeforeReset)*60) { // interval je prosao, punimo token bucket ponovo i resetujemo vreme
+		return now(), tokensPerReset
 	}
-	return nil
+	return timestamp, binary.LittleEndian.Uint32(val[8:12])
 }
 
-// InitializeTokenBucketConfigs - funkcija koja inicijalizuje konfiguracije potrebne za funkcionisanje token bucket-a
-func InitializeTokenBucketConfigs(tokensPerReset_ uint32, minutesBeforeReset_ uint32) {
-	tokensPerReset = tokensPerReset_
-	minutesBeforeReset = minutesBeforeReset_
-}
-
-// CheckTokenBucket - funkcija koja implementira token bucket algoritam
-func CheckTokenBucket(user string) bool {
-
-	val := system.get("", user)
-
-	if len(val) <= 0 { // ovaj korisnik prvi put pravi zahtev, dozvoli i puttuj inicijalne vrednosti za njega u mapu
-		system.put("", user, formInitialBytes())
-		return true
-	} else { // korisnik je vec pravio zahteve
-		timestamp := binary.LittleEndian.Uint64(val[:8])       // vreme proslog reseta
-		if isPast(timestamp + uint64(minutesBeforeReset)*60) { // interval je prosao, punimo token bucket ponovo i resetujemo vreme
-			system.put("", user, formInitialBytes())
-			return true
-		} else { // interval nije prosao
-			tokens := binary.LittleEndian.Uint32(val[8:])
-			if tokens >= 1 { // jos ima tokena, oduzimamo 1 token i dozvoljavamo
-				system.put("", user, formBytes(timestamp, tokens-1))
-				return true
-			} else { // nema vise tokena, zahtev odbijen
-				fmt.Println("Previse zahteva u ovom periodu vremena, zahtev odbijen. Molim Vas sacekajte.")
-				return false
-			}
-		}
+// TokenBucketRetryAfter - vraca broj sekundi do sledeceg punjenja baketa korisnika, 0 ako korisnik vec ima n tokena
+func TokenBucketRetryAfter(user string, n uint32) uint64 {
+	timestamp, tokens := readBucket(user)
+	reset := timestamp + uint64(minutesBeforeReset)*60
+	if tokens >= n || isPast(reset) {
+		return 0
 	}
+	return reset - now() + 1 // baket se puni tek kada vreme reseta prodje
 }
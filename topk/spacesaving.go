@@ -0,0 +1,113 @@
+package topk
+
+import (
+	"container/heap"
+	"sort"
+)
+
+//Procenjen broj pristupa kljucu
+//Stvaran broj je izmedju Count-Error i Count
+type Counter struct {
+	Key   string
+	Count uint64
+	Error uint64
+}
+
+//Space-Saving algoritam: prati najvise capacity kljuceva, a kada je pun novi kljuc zamenjuje
+//kljuc sa najmanjim brojem i nasledjuje njegov broj (koji postaje greska procene)
+//Svaki kljuc cesci od ukupno/capacity je sigurno medju pracenim
+type SpaceSaving struct {
+	capacity int
+	items    map[string]*item
+	heap     itemHeap
+	total    uint64
+}
+
+type item struct {
+	counter Counter
+	index   int
+}
+
+//Min heap po broju, na vrhu je kljuc koji se izbacuje
+type itemHeap []*item
+
+func (h itemHeap) Len() int           { return len(h) }
+func (h itemHeap) Less(i, j int) bool { return h[i].counter.Count < h[j].counter.Count }
+func (h itemHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *itemHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+func (h *itemHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+func NewSpaceSaving(capacity int) *SpaceSaving {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &SpaceSaving{capacity: capacity, items: make(map[string]*item)}
+}
+
+func (s *SpaceSaving) Add(key string, count uint64) {
+	s.total += count
+	if it, found := s.items[key]; found {
+		it.counter.Count += count
+		heap.Fix(&s.heap, it.index)
+		return
+	}
+	if len(s.items) < s.capacity {
+		it := &item{counter: Counter{Key: key, Count: count}}
+		s.items[key] = it
+		heap.Push(&s.heap, it)
+		return
+	}
+	it := s.heap[0]
+	delete(s.items, it.counter.Key)
+	it.counter = Counter{Key: key, Count: it.counter.Count + count, Error: it.counter.Count}
+	s.items[key] = it
+	heap.Fix(&s.heap, 0)
+}
+
+//Procenjen broj pristupa kljucu, 0 ako se kljuc ne prati
+func (s *SpaceSaving) Count(key string) uint64 {
+	if it, found := s.items[key]; found {
+		return it.counter.Count
+	}
+	return 0
+}
+
+//Ukupan broj pristupa
+func (s *SpaceSaving) Total() uint64 {
+	return s.total
+}
+
+//n kljuceva sa najvecim brojem pristupa, od najcesceg
+func (s *SpaceSaving) Top(n int) []Counter {
+	counters := make([]Counter, 0, len(s.items))
+	for _, it := range s.items {
+		counters = append(counters, it.counter)
+	}
+	return top(counters, n)
+}
+
+func top(counters []Counter, n int) []Counter {
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count != counters[j].Count {
+			return counters[i].Count > counters[j].Count
+		}
+		return counters[i].Key < counters[j].Key
+	})
+	if n >= 0 && len(counters) > n {
+		counters = counters[:n]
+	}
+	return counters
+}
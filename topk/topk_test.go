@@ -0,0 +1,86 @@
+package topk
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//Za svaki praceni kljuc je Count-Error <= stvaran broj <= Count, greska je najvise ukupno/capacity,
+//a svaki kljuc cesci od ukupno/capacity je medju pracenim
+func TestSpaceSavingErrorBound(t *testing.T) {
+	const capacity = 50
+	summary := NewSpaceSaving(capacity)
+	random := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(random, 1.1, 1, 999)
+	counts := make(map[string]uint64)
+	total := uint64(0)
+	for i := 0; i < 100000; i++ {
+		key := "k" + strconv.FormatUint(zipf.Uint64(), 10)
+		count := uint64(1 + random.Intn(2))
+		summary.Add(key, count)
+		counts[key] += count
+		total += count
+	}
+	if summary.Total() != total {
+		t.Fatalf("ocekivano ukupno %d, dobijeno %d", total, summary.Total())
+	}
+	tracked := summary.Top(-1)
+	if len(tracked) != capacity {
+		t.Fatalf("ocekivano %d pracenih kljuceva, dobijeno %d", capacity, len(tracked))
+	}
+	bound := total / capacity
+	for _, counter := range tracked {
+		actual := counts[counter.Key]
+		if counter.Count-counter.Error > actual || actual > counter.Count {
+			t.Fatalf("%s: stvaran broj %d van [%d, %d]", counter.Key, actual, counter.Count-counter.Error, counter.Count)
+		}
+		if counter.Error > bound {
+			t.Fatalf("%s: greska %d veca od ukupno/capacity %d", counter.Key, counter.Error, bound)
+		}
+	}
+	for key, count := range counts {
+		if count > bound && summary.Count(key) == 0 {
+			t.Fatalf("%s sa %d pristupa (preko %d) se ne prati", key, count, bound)
+		}
+	}
+	if top := summary.Top(3); len(top) != 3 || top[0].Key != "k0" || top[0].Count < top[1].Count || top[1].Count < top[2].Count {
+		t.Fatalf("ocekivana tri najcesca kljuca od k0, dobijeno %v", top)
+	}
+}
+
+//Pristupi izlaze iz prozora deo po deo, vreme se pomera kroz w.now
+func TestWindowExpiry(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	w := NewWindow(10, 10*time.Second, 5)
+	w.now = func() time.Time { return clock }
+
+	w.Add("a")
+	w.Add("a")
+	clock = clock.Add(5 * time.Second)
+	w.Add("b")
+	clock = clock.Add(4900 * time.Millisecond)
+	if w.Count("a") != 2 || w.Count("b") != 1 {
+		t.Fatalf("pre isteka ocekivano a=2, b=1, dobijeno a=%d, b=%d", w.Count("a"), w.Count("b"))
+	}
+	if top := w.Top(1); len(top) != 1 || top[0].Key != "a" {
+		t.Fatalf("ocekivan a kao najcesci, dobijeno %v", top)
+	}
+
+	clock = clock.Add(200 * time.Millisecond)
+	if w.Count("a") != 0 || w.Count("b") != 1 {
+		t.Fatalf("posle isteka prvog dela ocekivano a=0, b=1, dobijeno a=%d, b=%d", w.Count("a"), w.Count("b"))
+	}
+	if top := w.Top(-1); len(top) != 1 || top[0].Key != "b" {
+		t.Fatalf("ocekivan samo b, dobijeno %v", top)
+	}
+
+	clock = clock.Add(time.Minute)
+	if top := w.Top(-1); len(top) != 0 {
+		t.Fatalf("ocekivan prazan prozor, dobijeno %v", top)
+	}
+	if len(w.slices) != 1 {
+		t.Fatalf("ocekivan samo tekuci deo, dobijeno %d delova", len(w.slices))
+	}
+}
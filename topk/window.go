@@ -0,0 +1,92 @@
+package topk
+
+import (
+	"sync"
+	"time"
+)
+
+//Top-K u kliznom vremenskom prozoru
+//Prozor je podeljen na slices delova, svaki deo ima svoj Space-Saving, a najstariji deo se
+//odbacuje kada istekne. Zbog toga prozor klizi u koracima od window/slices
+type Window struct {
+	mutex    sync.Mutex
+	capacity int
+	slice    time.Duration
+	count    int //broj delova prozora
+	slices   []*windowSlice
+	now      func() time.Time
+}
+
+type windowSlice struct {
+	start   time.Time
+	summary *SpaceSaving
+}
+
+func NewWindow(capacity int, window time.Duration, slices int) *Window {
+	if slices < 1 {
+		slices = 1
+	}
+	slice := window / time.Duration(slices)
+	if slice <= 0 {
+		slice = time.Second
+	}
+	return &Window{capacity: capacity, slice: slice, count: slices, slices: make([]*windowSlice, 0, slices), now: time.Now}
+}
+
+//Odbacuje delove starije od prozora i vraca tekuci deo
+func (w *Window) current() *windowSlice {
+	now := w.now()
+	start := now.Truncate(w.slice)
+	oldest := start.Add(-w.slice * time.Duration(w.count-1))
+	i := 0
+	for i < len(w.slices) && w.slices[i].start.Before(oldest) {
+		i++
+	}
+	w.slices = append(w.slices[:0], w.slices[i:]...)
+	if len(w.slices) == 0 || !w.slices[len(w.slices)-1].start.Equal(start) {
+		w.slices = append(w.slices, &windowSlice{start: start, summary: NewSpaceSaving(w.capacity)})
+	}
+	return w.slices[len(w.slices)-1]
+}
+
+func (w *Window) Add(key string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.current().summary.Add(key, 1)
+}
+
+//Procenjen broj pristupa kljucu u prozoru
+func (w *Window) Count(key string) uint64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.current()
+	count := uint64(0)
+	for _, s := range w.slices {
+		count += s.summary.Count(key)
+	}
+	return count
+}
+
+//n najcescih kljuceva u prozoru - brojevi i greske delova se sabiraju
+func (w *Window) Top(n int) []Counter {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.current()
+	merged := make(map[string]*Counter)
+	for _, s := range w.slices {
+		for _, it := range s.summary.items {
+			counter, found := merged[it.counter.Key]
+			if !found {
+				counter = &Counter{Key: it.counter.Key}
+				merged[it.counter.Key] = counter
+			}
+			counter.Count += it.counter.Count
+			counter.Error += it.counter.Error
+		}
+	}
+	counters := make([]Counter, 0, len(merged))
+	for _, counter := range merged {
+		counters = append(counters, *counter)
+	}
+	return top(counters, n)
+}
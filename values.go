@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"kv/bloom"
+	"kv/cms"
+	"kv/hll"
+	"kv/simhash"
+	"strings"
+)
+
+//Vrednosti sa tipom (npr. bloom filter aplikacije) cuvaju se pod kljucem kao obicne vrednosti, kroz put,
+//pa se upisuju u WAL i SSTabele kao i ostali podaci. Prva 4 bajta vrednosti oznacavaju tip
+const (
+	bloomValue = "BLMF"
+	hllValue   = "HLLS"
+	cmsValue   = "CMSK"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+	ErrWrongType   = errors.New("value under key has a different type")
+
+	errWriteFailed = errors.New("upis nije uspeo")
+)
+
+//Trosi token korisnika, ako je zadat
+func chargeUser(user string) error {
+	if user != "" && !CheckTokenBucket(user) {
+		return fmt.Errorf("korisnik %s nema vise tokena", user)
+	}
+	return nil
+}
+
+//Cita vrednost kljuca i skida zaglavlje tipa
+func (sys *System) getTyped(key string, tag string) ([]byte, error) {
+	value := sys.read(key)
+	if value == nil {
+		return nil, ErrKeyNotFound
+	}
+	if len(value) < len(tag) || !bytes.Equal(value[:len(tag)], []byte(tag)) {
+		return nil, ErrWrongType
+	}
+	return value[len(tag):], nil
+}
+
+//Upisuje vrednost sa zaglavljem tipa
+func (sys *System) putTyped(key string, tag string, payload []byte) bool {
+	value := make([]byte, 0, len(tag)+len(payload))
+	value = append(value, tag...)
+	value = append(value, payload...)
+	return sys.write(key, value)
+}
+
+//Granice velicine bloom filtera aplikacije - najveci filter ima oko 360KB
+//Svaki BloomAdd ponovo upisuje ceo filter kroz WAL i memtabelu, pa granica mora ostati mala
+const (
+	maxBloomElements = 100000
+	minBloomRate     = 1e-6
+)
+
+//Pravi prazan bloom filter pod kljucem, postojeca vrednost kljuca se prepisuje
+func (sys *System) BloomCreate(user string, key string, expected int, falsePositiveRate float64) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	if expected < 1 || expected > maxBloomElements || falsePositiveRate < minBloomRate || falsePositiveRate >= 1 {
+		return fmt.Errorf("bloom filter: broj elemenata mora biti od 1 do %d, a stopa laznih pozitiva od %g do 1", maxBloomElements, minBloomRate)
+	}
+	if !sys.putTyped(key, bloomValue, bloom.New(expected, falsePositiveRate).Marshal()) {
+		return errWriteFailed
+	}
+	sys.dropExpire(key)
+	return nil
+}
+
+func (sys *System) loadBloom(key string) (*bloom.BloomFilter, error) {
+	payload, err := sys.getTyped(key, bloomValue)
+	if err != nil {
+		return nil, err
+	}
+	return bloom.Unmarshal(payload)
+}
+
+//Dodaje elemente u bloom filter sacuvan pod kljucem
+//Za svaki element vraca da li je dodat prvi put, tj. da li ga filter pre toga sigurno nije sadrzao
+func (sys *System) BloomAdd(user string, key string, items ...string) ([]bool, error) {
+	if err := chargeUser(user); err != nil {
+		return nil, err
+	}
+	filter, err := sys.loadBloom(key)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]bool, len(items))
+	for i, item := range items {
+		added[i] = !filter.Contains(item)
+		filter.Add(item)
+	}
+	if !sys.putTyped(key, bloomValue, filter.Marshal()) {
+		return nil, errWriteFailed
+	}
+	return added, nil
+}
+
+//Proverava element u bloom filteru sacuvanom pod kljucem - true znaci "mozda postoji", false "sigurno ne postoji"
+func (sys *System) BloomContains(user string, key string, item string) (bool, error) {
+	if err := chargeUser(user); err != nil {
+		return false, err
+	}
+	filter, err := sys.loadBloom(key)
+	if err != nil {
+		return false, err
+	}
+	return filter.Contains(item), nil
+}
+
+//Preciznost skice koju RESP komanda PFADD pravi za nepostojeci kljuc, kao u Redis-u (greska procene oko 0.8%)
+const defaultHLLPrecision = 14
+
+//Pravi praznu HyperLogLog skicu pod kljucem, preciznost je izmedju 4 i 16 bitova
+func (sys *System) HLLCreate(user string, key string, precision uint8) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	sketch, err := hll.New(precision)
+	if err != nil {
+		return err
+	}
+	if !sys.putTyped(key, hllValue, sketch.Marshal()) {
+		return errWriteFailed
+	}
+	return nil
+}
+
+func (sys *System) loadHLL(key string) (*hll.HLL, error) {
+	payload, err := sys.getTyped(key, hllValue)
+	if err != nil {
+		return nil, err
+	}
+	return hll.Unmarshal(payload)
+}
+
+//Dodaje elemente u skicu sacuvanu pod kljucem i vraca da li se skica promenila
+func (sys *System) HLLAdd(user string, key string, items ...string) (bool, error) {
+	if err := chargeUser(user); err != nil {
+		return false, err
+	}
+	sketch, err := sys.loadHLL(key)
+	if err != nil {
+		return false, err
+	}
+	before := sketch.Marshal()
+	for _, item := range items {
+		sketch.Add(item)
+	}
+	after := sketch.Marshal()
+	if bytes.Equal(before, after) {
+		return false, nil
+	}
+	if !sys.putTyped(key, hllValue, after) {
+		return false, errWriteFailed
+	}
+	return true, nil
+}
+
+//Procena broja razlicitih elemenata skice sacuvane pod kljucem
+func (sys *System) HLLEstimate(user string, key string) (uint64, error) {
+	if err := chargeUser(user); err != nil {
+		return 0, err
+	}
+	sketch, err := sys.loadHLL(key)
+	if err != nil {
+		return 0, err
+	}
+	return sketch.Estimate(), nil
+}
+
+//Spaja skice sacuvane pod kljucevima sources u skicu pod kljucem key
+//Ako skica pod kljucem key ne postoji, pravi se od prve izvorne skice, kao PFMERGE u Redis-u
+func (sys *System) HLLMerge(user string, key string, sources ...string) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	sketch, err := sys.loadHLL(key)
+	if err == ErrKeyNotFound && len(sources) > 0 {
+		sketch, err = sys.loadHLL(sources[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", sources[0], err)
+		}
+		sources = sources[1:]
+	}
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		other, err := sys.loadHLL(source)
+		if err == nil {
+			err = sketch.Merge(other)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	if !sys.putTyped(key, hllValue, sketch.Marshal()) {
+		return errWriteFailed
+	}
+	return nil
+}
+
+//Pravi praznu Count-Min skicu pod kljucem
+//Procena je sa verovatnocom 1-delta veca od stvarne za najvise epsilon*zbir svih brojanja
+func (sys *System) CMSCreate(user string, key string, epsilon float64, delta float64) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	sketch, err := cms.New(epsilon, delta)
+	if err != nil {
+		return err
+	}
+	if !sys.putTyped(key, cmsValue, sketch.Marshal()) {
+		return errWriteFailed
+	}
+	sys.dropExpire(key)
+	return nil
+}
+
+//Pravi praznu skicu pod kljucem sa dimenzijama i semenom skice pod kljucem source, pa se one mogu sabrati
+//Nezavisno napravljene skice uglavnom imaju razlicito seme i ne mogu se sabrati
+func (sys *System) CMSCreateLike(user string, key string, source string) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	sketch, err := sys.loadCMS(source)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if !sys.putTyped(key, cmsValue, sketch.Compatible().Marshal()) {
+		return errWriteFailed
+	}
+	sys.dropExpire(key)
+	return nil
+}
+
+func (sys *System) loadCMS(key string) (*cms.CountMinSketch, error) {
+	payload, err := sys.getTyped(key, cmsValue)
+	if err != nil {
+		return nil, err
+	}
+	return cms.Unmarshal(payload)
+}
+
+//Dodaje count pojavljivanja elementa u skicu sacuvanu pod kljucem i vraca novu procenu
+func (sys *System) CMSAdd(user string, key string, item string, count uint64) (uint64, error) {
+	if err := chargeUser(user); err != nil {
+		return 0, err
+	}
+	sketch, err := sys.loadCMS(key)
+	if err != nil {
+		return 0, err
+	}
+	sketch.Add(item, count)
+	if !sys.putTyped(key, cmsValue, sketch.Marshal()) {
+		return 0, errWriteFailed
+	}
+	return sketch.Estimate(item), nil
+}
+
+//Procena broja pojavljivanja elementa u skici sacuvanoj pod kljucem
+func (sys *System) CMSEstimate(user string, key string, item string) (uint64, error) {
+	if err := chargeUser(user); err != nil {
+		return 0, err
+	}
+	sketch, err := sys.loadCMS(key)
+	if err != nil {
+		return 0, err
+	}
+	return sketch.Estimate(item), nil
+}
+
+//Sabira skice sacuvane pod kljucevima sources u skicu pod kljucem key
+//Skice se mogu sabrati samo ako imaju iste dimenzije i seme. Ako skica pod kljucem key ne postoji, pravi se od prve izvorne
+func (sys *System) CMSMerge(user string, key string, sources ...string) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	sketch, err := sys.loadCMS(key)
+	if err == ErrKeyNotFound && len(sources) > 0 {
+		sketch, err = sys.loadCMS(sources[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", sources[0], err)
+		}
+		sources = sources[1:]
+	}
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		other, err := sys.loadCMS(source)
+		if err == nil {
+			err = sketch.Merge(other)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	if !sys.putTyped(key, cmsValue, sketch.Marshal()) {
+		return errWriteFailed
+	}
+	return nil
+}
+
+//Otisak dokumenta se cuva pod rezervisanim kljucem, pored samog dokumenta
+//Indeks se gradi iz njih prefiksnim skeniranjem pri prvoj upotrebi, pa prezivljava restart
+//Obican upis ili brisanje kljuca dokumenta brise i njegov otisak (dropDocument), pa indeks ne sadrzi zastarele otiske
+const (
+	simhashPrefix   = reservedPrefix + "simhash/"
+	simhashDistance = 3 //najveca udaljenost koju indeks pokriva bez poredjenja svih otisaka
+)
+
+func (sys *System) documentIndex() *simhash.Index {
+	if sys.documents == nil {
+		sys.documents = simhash.NewIndex(simhashDistance)
+		for _, kv := range sys.reservedScan(simhashPrefix) {
+			if len(kv.value) == 8 {
+				sys.documents.Add(strings.TrimPrefix(kv.key, simhashPrefix), binary.LittleEndian.Uint64(kv.value))
+			}
+		}
+	}
+	return sys.documents
+}
+
+//Izbacuje otisak kljuca iz indeksa i brise ga, poziva se posle svakog upisa i brisanja korisnickog kljuca
+func (sys *System) dropDocument(key string) {
+	if isReservedKey(key) {
+		return
+	}
+	index := sys.documentIndex()
+	if index.Contains(key) {
+		index.Remove(key)
+		sys.Delete("", simhashPrefix+key)
+	}
+}
+
+//Upisuje tekst dokumenta pod kljucem i pored njega njegov SimHash otisak
+func (sys *System) PutDocument(user string, key string, text string) error {
+	if err := chargeUser(user); err != nil {
+		return err
+	}
+	fingerprint := simhash.FromText(text)
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, fingerprint)
+	if !sys.write(key, []byte(text)) || !sys.write(simhashPrefix+key, bytes) {
+		return errWriteFailed
+	}
+	sys.documentIndex().Add(key, fingerprint)
+	sys.dropExpire(key)
+	return nil
+}
+
+//Vraca kljuceve dokumenata ciji je otisak na Hamming udaljenosti najvise distance (0-64) od otiska teksta
+func (sys *System) NearDuplicates(user string, text string, distance int) ([]simhash.Match, error) {
+	if err := chargeUser(user); err != nil {
+		return nil, err
+	}
+	if distance < 0 || distance > 64 {
+		return nil, errors.New("udaljenost mora biti od 0 do 64")
+	}
+	return sys.documentIndex().Query(simhash.FromText(text), distance), nil
+}
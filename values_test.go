@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+//Najveci dozvoljen bloom filter ostaje mali jer ga svaki BloomAdd ponovo upisuje ceo
+func TestBloomSizeLimit(t *testing.T) {
+	sys := newTestSystem(t)
+	if err := sys.BloomCreate("", "veliki", maxBloomElements+1, 0.01); err == nil {
+		t.Fatal("ocekivana greska za filter preko maxBloomElements")
+	}
+	if err := sys.BloomCreate("", "najveci", maxBloomElements, minBloomRate); err != nil {
+		t.Fatal(err)
+	}
+	if size := len(sys.read("najveci")); size > 400*1024 {
+		t.Fatalf("najveci filter ima %d bajtova", size)
+	}
+	added, err := sys.BloomAdd("", "najveci", "a", "a")
+	if err != nil || !added[0] || added[1] {
+		t.Fatalf("ocekivano [true false], dobijeno %v, %v", added, err)
+	}
+	if found, _ := sys.BloomContains("", "najveci", "a"); !found {
+		t.Fatal("dodat element nije u filteru")
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -10,6 +9,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -37,13 +37,22 @@ var (
 	segmentSize  = 10
 	lowWaterMark = 4
 
-	ErrCorrupted   = errors.New("log corrupted")
 	ErrOutOfBounds = errors.New("index out of bounds")
 	ErrNotFound    = errors.New("file not found")
 
 	log *Log
 )
 
+// ErrCorruptedWAL - zapis segmenta je ostecen (neispravan CRC, zaglavlje ili duzina)
+type ErrCorruptedWAL struct {
+	Segment string
+	Offset  uint64
+}
+
+func (e *ErrCorruptedWAL) Error() string {
+	return fmt.Sprintf("corrupted WAL record in %s at offset %d", e.Segment, e.Offset)
+}
+
 func fileLen(file *os.File) (int64, error) {
 	info, err := file.Stat()
 	if err != nil {
@@ -135,7 +144,11 @@ func (log *Log) Close() error {
 
 func countEntries(file *os.File) (int, error) {
 	for i := 0; ; i++ {
-		_, err := file.Seek(12, 1)
+		offset, err := file.Seek(0, 1)
+		if err != nil {
+			return -1, err
+		}
+		_, err = file.Seek(12, 1)
 		if err != nil {
 			return -1, err
 		}
@@ -176,67 +189,50 @@ func countEntries(file *os.File) (int, error) {
 				return -1, err
 			}
 		} else {
-			return -1, ErrCorrupted
+			return -1, &ErrCorruptedWAL{Segment: file.Name(), Offset: uint64(offset)}
 		}
 	}
 }
 
 func FormBytesPut(key string, value []byte) []byte {
-	bytes := make([]byte, 29+len(key)+len(value))                              // 4+8+1+8+8 = 29 dužina jednog entry-a write ahead loga bez ključa batchNum vrednosti
-	binary.LittleEndian.PutUint32(bytes[:4], CRC32([]byte(key)))               // CRC - 4B
-	binary.LittleEndian.PutUint64(bytes[4:12], uint64(time.Now().UnixMicro())) // Timestamp - 8B
-	bytes[12] = 0                                                              // Tombstone - 1B
-	binary.LittleEndian.PutUint64(bytes[13:21], uint64(len(key)))              // Key size - 8B
-	binary.LittleEndian.PutUint64(bytes[21:29], uint64(len(value)))            // Value size - 8B
-	for i := 0; i < len(key); i++ {                                            // Key postavljen
+	return formBytesPutAt(key, value, uint64(time.Now().UnixMicro()))
+}
+
+// formBytesPutAt - kao FormBytesPut, ali sa zadatim vremenom, da bi zapis iz WAL-a zadrzao svoje vreme pri ponovnom izvrsavanju
+func formBytesPutAt(key string, value []byte, timestamp uint64) []byte {
+	bytes := make([]byte, 29+len(key)+len(value))                   // 4+8+1+8+8 = 29 dužina jednog entry-a write ahead loga bez ključa batchNum vrednosti
+	binary.LittleEndian.PutUint64(bytes[4:12], timestamp)           // Timestamp - 8B
+	bytes[12] = 0                                                   // Tombstone - 1B
+	binary.LittleEndian.PutUint64(bytes[13:21], uint64(len(key)))   // Key size - 8B
+	binary.LittleEndian.PutUint64(bytes[21:29], uint64(len(value))) // Value size - 8B
+	for i := 0; i < len(key); i++ {                                 // Key postavljen
 		bytes[29+i] = key[i]
 	}
 	for i := 0; i < len(value); i++ { // Value postavljen
 		bytes[29+len(key)+i] = value[i]
 	}
+	binary.LittleEndian.PutUint32(bytes[:4], CRC32(bytes[4:])) // CRC - 4B, racuna se nad celim zapisom
 
 	return bytes
 }
 
 func FormBytesDelete(key string) []byte {
 	bytes := make([]byte, 21+len(key))                                         // 4+8+1+8 = 21 dužina jednog entry-a write ahead loga bez ključa, vrednosti ali batchNum value size-a jer je ovde nepotreban
-	binary.LittleEndian.PutUint32(bytes[:4], CRC32([]byte(key)))               // CRC - 4B
 	binary.LittleEndian.PutUint64(bytes[4:12], uint64(time.Now().UnixMicro())) // Timestamp - 8B
 	bytes[12] = 1                                                              // Tombstone - 1B
 	binary.LittleEndian.PutUint64(bytes[13:21], uint64(len(key)))              // Key size - 8B
 	for i := 0; i < len(key); i++ {                                            // Key postavljen
 		bytes[21+i] = key[i]
 	}
+	binary.LittleEndian.PutUint32(bytes[:4], CRC32(bytes[4:])) // CRC - 4B, racuna se nad celim zapisom
 
 	return bytes
 }
 
-func (log *Log) checkWaterMark() error {
-	if log.endIndex > lowWaterMark {
-		err := log.file.Close()
-		if err != nil && !strings.Contains(err.Error(), fs.ErrClosed.Error()) {
-			return err
-		}
-		files, err := os.ReadDir("wal/")
-		if err != nil {
-			return err
-		}
-		var filesForDeletion []string
-
-		for _, f := range files {
-			if strings.HasPrefix(f.Name(), log.fileName+"_") {
-				filesForDeletion = append(filesForDeletion, "wal/"+f.Name())
-			}
-		}
-		for _, f := range filesForDeletion {
-			err := os.Remove(f)
-			if err != nil {
-				return err
-			}
-		}
-		log.endIndex = 0
-	}
-	return nil
+// OverWaterMark - WAL ima vise od lowWaterMark segmenata, pa memtabelu treba upisati u SSTabelu
+// Segmenti se brisu samo pri flush-u (RecreateWAL), jer svaki segment sadrzi zapise kojih jos nema u SSTabelama
+func (log *Log) OverWaterMark() bool {
+	return log.endIndex >= lowWaterMark
 }
 
 func (log *Log) WritePutDirect(key string, value []byte) error {
@@ -253,11 +249,6 @@ func (log *Log) WritePutDirect(key string, value []byte) error {
 	} else {
 		log.endIndex++
 
-		err := log.checkWaterMark()
-		if err != nil {
-			return err
-		}
-
 		log.currIndex = log.endIndex
 		log.entryNum = 0
 		file, err := os.Create("wal/" + log.fileName + "_" + strconv.Itoa(log.currIndex))
@@ -314,11 +305,6 @@ func (log *Log) WriteDeleteDirect(key string) error {
 	} else {
 		log.endIndex++
 
-		err := log.checkWaterMark()
-		if err != nil {
-			return err
-		}
-
 		log.currIndex = log.endIndex
 		log.entryNum = 0
 		file, err := os.Create("wal/" + log.fileName + "_" + strconv.Itoa(log.currIndex))
@@ -362,9 +348,14 @@ func (log *Log) WriteDeleteBuffer(key string) error {
 }
 
 func (log *Log) writeBuffer(bytes []byte) error {
-	log.batch[log.batchNum] = bytes
+	//batchSize se moze promeniti ponovnim ucitavanjem konfiguracije, pa batch ne mora biti te duzine
+	if log.batchNum < len(log.batch) {
+		log.batch[log.batchNum] = bytes
+	} else {
+		log.batch = append(log.batch, bytes)
+	}
 	log.batchNum++
-	if log.batchNum == batchSize {
+	if log.batchNum >= batchSize {
 		err := log.writeBatch()
 		if err != nil {
 			return err
@@ -418,10 +409,6 @@ func (log *Log) writeBatch() error {
 			log.entryNum++
 		} else {
 			log.endIndex++
-			err := log.checkWaterMark()
-			if err != nil {
-				return err
-			}
 			log.currIndex = log.endIndex
 			log.entryNum = 0
 			file, err := os.Create("wal/" + log.fileName + "_" + strconv.Itoa(log.currIndex))
@@ -445,72 +432,110 @@ func (log *Log) writeBatch() error {
 	return nil
 }
 
-func (log *Log) ReadAll() ([]EntryWAL, error) {
-	var entries []EntryWAL
+// readEntry - cita jedan zapis sa trenutne pozicije u segmentu i proverava CRC nad celim zapisom
+// Vraca io.EOF kada u segmentu nema vise zapisa, a ErrCorruptedWAL ukoliko je zapis ostecen
+func readEntry(file *os.File) (*EntryWAL, error) {
+	offset, err := file.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	corrupted := &ErrCorruptedWAL{Segment: file.Name(), Offset: uint64(offset)}
 
-	for i := 0; i <= log.endIndex; i++ {
-		file, err := os.Open("wal/" + log.fileName + "_" + strconv.Itoa(i))
-		defer file.Close()
-		if err != nil {
-			return entries, err
+	entry := EntryWAL{}
+
+	var header = make([]byte, 21)
+
+	_, err = io.ReadFull(file, header)
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, corrupted
 		}
+		return nil, err
+	}
 
-		for {
-			entry := EntryWAL{}
+	crc := binary.LittleEndian.Uint32(header[:4])
+	entry.timestamp = binary.LittleEndian.Uint64(header[4:12])
+	entry.tombstone = header[12]
+	keysize := binary.LittleEndian.Uint64(header[13:21])
 
-			var data = make([]byte, 21)
+	size, err := fileLen(file)
+	if err != nil {
+		return nil, err
+	}
 
-			_, err = file.Read(data)
+	var record []byte
+	if entry.tombstone == 0 {
+		var data = make([]byte, 8)
 
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				return nil, err
-			}
+		_, err = io.ReadFull(file, data)
+		if err != nil {
+			return nil, corrupted
+		}
 
-			crc := binary.LittleEndian.Uint32(data[:4])
-			entry.timestamp = binary.LittleEndian.Uint64(data[4:12])
-			entry.tombstone = data[12]
-			keysize := binary.LittleEndian.Uint64(data[13:21])
+		valuesize := binary.LittleEndian.Uint64(data[:8])
+		if keysize+valuesize > uint64(size) {
+			return nil, corrupted
+		}
 
-			if entry.tombstone == 0 {
-				data = make([]byte, 8)
+		record = make([]byte, 29+keysize+valuesize)
+		copy(record, header)
+		copy(record[21:], data)
 
-				_, err = file.Read(data)
-				if err != nil {
-					return nil, err
-				}
+		_, err = io.ReadFull(file, record[29:])
+		if err != nil {
+			return nil, corrupted
+		}
 
-				valuesize := binary.LittleEndian.Uint64(data[:8])
+		entry.key = string(record[29 : 29+keysize])
+		entry.value = record[29+keysize:]
+	} else if entry.tombstone == 1 {
+		if keysize > uint64(size) {
+			return nil, corrupted
+		}
 
-				data = make([]byte, keysize+valuesize)
+		record = make([]byte, 21+keysize)
+		copy(record, header)
 
-				_, err = file.Read(data)
-				if err != nil {
-					return nil, err
-				}
+		_, err = io.ReadFull(file, record[21:])
+		if err != nil {
+			return nil, corrupted
+		}
 
-				entry.key = string(data[:keysize])
-				entry.value = data[keysize : keysize+valuesize]
-			} else if entry.tombstone == 1 {
-				data = make([]byte, keysize)
+		entry.key = string(record[21:])
+	} else {
+		return nil, corrupted
+	}
 
-				_, err = file.Read(data)
-				if err != nil {
-					return nil, err
-				}
+	if CRC32(record[4:]) != crc {
+		return nil, corrupted
+	}
 
-				entry.key = string(data[:])
-			} else {
-				return nil, ErrCorrupted
-			}
+	return &entry, nil
+}
+
+func (log *Log) ReadAll() ([]EntryWAL, error) {
+	var entries []EntryWAL
 
-			if CRC32([]byte(entry.key)) != crc {
-				return nil, ErrCorrupted
+	for i := 0; i <= log.endIndex; i++ {
+		file, err := os.Open("wal/" + log.fileName + "_" + strconv.Itoa(i))
+		if err != nil {
+			return entries, err
+		}
+		defer file.Close()
+
+		for {
+			entry, err := readEntry(file)
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
 			}
 
-			entries = append(entries, entry)
+			entries = append(entries, *entry)
 		}
 	}
 
@@ -521,110 +546,22 @@ func (log *Log) ReadAt(index int) (*EntryWAL, error) {
 	j := 0
 	for i := 0; i <= log.endIndex; i++ {
 		file, err := os.Open("wal/" + log.fileName + "_" + strconv.Itoa(i))
-		defer file.Close()
 		if err != nil {
 			return nil, err
 		}
+		defer file.Close()
 
 		for {
-			if j != index {
-				_, err := file.Seek(12, 1)
-				if err != nil {
-					return nil, err
-				}
-
-				var data = make([]byte, 9)
-
-				_, err = file.Read(data)
-
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					return nil, err
-				}
-
-				tombstone := data[0]
-				keysize := binary.LittleEndian.Uint64(data[1:])
-
-				if tombstone == 0 {
-					data = make([]byte, 8)
-
-					_, err = file.Read(data)
-					if err != nil {
-						return nil, err
-					}
-
-					valuesize := binary.LittleEndian.Uint64(data)
-
-					_, err := file.Seek(int64(keysize+valuesize), 1)
-					if err != nil {
-						return nil, err
-					}
-				} else if tombstone == 1 {
-					_, err := file.Seek(int64(keysize), 1)
-					if err != nil {
-						return nil, err
-					}
-				} else {
-					return nil, ErrCorrupted
-				}
-			} else {
-				entry := EntryWAL{}
-
-				var data = make([]byte, 21)
-
-				_, err = file.Read(data)
-
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					return nil, err
-				}
-
-				crc := binary.LittleEndian.Uint32(data[:4])
-				entry.timestamp = binary.LittleEndian.Uint64(data[4:12])
-				entry.tombstone = data[12]
-				keysize := binary.LittleEndian.Uint64(data[13:21])
-
-				if entry.tombstone == 0 {
-					data = make([]byte, 8)
-
-					_, err = file.Read(data)
-					if err != nil {
-						return nil, err
-					}
-
-					valuesize := binary.LittleEndian.Uint64(data[:8])
-
-					data = make([]byte, keysize+valuesize)
-
-					_, err = file.Read(data)
-					if err != nil {
-						return nil, err
-					}
-
-					entry.key = string(data[:keysize])
-					entry.value = data[keysize : keysize+valuesize]
-				} else if entry.tombstone == 1 {
-					data = make([]byte, keysize)
-
-					_, err = file.Read(data)
-					if err != nil {
-						return nil, err
-					}
-
-					entry.key = string(data[:])
-				} else {
-					return nil, ErrCorrupted
-				}
-
-				if CRC32([]byte(entry.key)) != crc {
-					return nil, ErrCorrupted
+			// i preskoceni zapisi se proveravaju, jer se bez ispravnog zaglavlja ne zna gde pocinje sledeci
+			entry, err := readEntry(file)
+			if err != nil {
+				if err == io.EOF {
+					break
 				}
-
-				return &entry, nil
+				return nil, err
+			}
+			if j == index {
+				return entry, nil
 			}
 			j++
 		}
@@ -633,44 +570,54 @@ func (log *Log) ReadAt(index int) (*EntryWAL, error) {
 	return nil, ErrOutOfBounds
 }
 
-// ClearWALFolder - funkcija koja cisti folder koji sadrzi sve WAL segmente
-func ClearWALFolder() {
-	err := os.RemoveAll("wal/")
+// ReplayWAL - cita zapise koje je prethodno pokretanje ostavilo u WAL-u iz direktorijuma dir, redom segmenata
+// WAL se brise pri svakom flush-u memtabele, pa su u njemu samo zapisi koji jos nisu u SSTabelama
+// Ostecen zapis (npr. upis prekinut padom) prekida citanje i vraca gresku
+func ReplayWAL(dir string) ([]EntryWAL, error) {
+	files, err := os.ReadDir(dir)
 	if err != nil {
-		return
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
-}
-
-// LoadConfigurations - funkcija koja ucitava sve inicijalne konfiguracije wal-a
-func LoadConfigurations() error {
-	config, err := os.Open("config.txt")
-	if err != nil {
-		return err
+	var indexes []int
+	for _, f := range files {
+		index, err := strconv.Atoi(strings.TrimPrefix(f.Name(), "wal_"))
+		if err == nil && strings.HasPrefix(f.Name(), "wal_") {
+			indexes = append(indexes, index)
+		}
 	}
+	sort.Ints(indexes)
 
-	scanner := bufio.NewScanner(config)
-
-	scanner.Scan()
-	batchSize, err = strconv.Atoi(scanner.Text()[10:])
-	if err != nil {
-		return err
-	}
-	scanner.Scan()
-	segmentSize, err = strconv.Atoi(scanner.Text()[12:])
-	if err != nil {
-		return err
-	}
-	scanner.Scan()
-	lowWaterMark, err = strconv.Atoi(scanner.Text()[13:])
-	if err != nil {
-		return err
+	var entries []EntryWAL
+	for _, index := range indexes {
+		file, err := os.Open(dir + "/wal_" + strconv.Itoa(index))
+		if err != nil {
+			return nil, err
+		}
+		for {
+			entry, err := readEntry(file)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			entries = append(entries, *entry)
+		}
+		file.Close()
 	}
+	return entries, nil
+}
 
-	err = config.Close()
+// ClearWALFolder - funkcija koja cisti folder koji sadrzi sve WAL segmente
+func ClearWALFolder() {
+	err := os.RemoveAll("wal/")
 	if err != nil {
-		return err
+		return
 	}
-	return nil
 }
 
 // InitializeWALConfigs - funkcija koja inicijalizuje konfiguracije potrebne za funkcionisanje wal-a
@@ -705,11 +652,6 @@ func RecreateWAL() error {
 }
 
 func test() {
-	/*err := LoadConfigurations() // podešavanja se sada učitavaju u mainu
-	if os.IsNotExist(err) {
-		fmt.Println("config.txt ne postoji, u upotrebi su podrazumevana podešavanja.")
-	}*/
-
 	ClearWALFolder()
 
 	var err error
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*Citanje konfiguracionih fajlova u ravnu mapu "sekcija.kljuc" -> vrednost
+Vrednosti ostaju tekst, a njihov tip proverava podesavanje kome pripadaju*/
+
+/*Podskup YAML-a dovoljan za konfiguraciju: ugnjezdene mape odredjene uvlacenjem (razmacima),
+skalari bez navodnika ili pod jednostrukim/dvostrukim navodnicima i komentari sa #
+Liste, sidra, visestruki dokumenti i visereda skalari nisu podrzani*/
+func parseYAML(reader io.Reader) (map[string]string, error) {
+	type section struct {
+		indent int
+		prefix string
+	}
+	values := make(map[string]string)
+	sections := []section{{indent: 0}}
+	//Sekcija otvorena u prethodnom redu - njen prvi kljuc odredjuje uvlacenje cele sekcije
+	opened := false
+	scanner := bufio.NewScanner(reader)
+	for number := 1; scanner.Scan(); number++ {
+		line := stripYAMLComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("red %d: uvlacenje tabom nije dozvoljeno", number)
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			return nil, fmt.Errorf("red %d: liste nisu podrzane", number)
+		}
+		if opened {
+			if indent <= sections[len(sections)-1].indent {
+				return nil, fmt.Errorf("red %d: sekcija %s je prazna", number, sections[len(sections)-1].prefix)
+			}
+			sections[len(sections)-1].indent = indent
+			opened = false
+		}
+		for indent < sections[len(sections)-1].indent {
+			sections = sections[:len(sections)-1]
+		}
+		if indent != sections[len(sections)-1].indent {
+			return nil, fmt.Errorf("red %d: neispravno uvlacenje", number)
+		}
+
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 || (colon+1 < len(trimmed) && trimmed[colon+1] != ' ') {
+			return nil, fmt.Errorf("red %d: ocekuje se \"kljuc: vrednost\"", number)
+		}
+		key := strings.TrimSpace(trimmed[:colon])
+		if prefix := sections[len(sections)-1].prefix; prefix != "" {
+			key = prefix + "." + key
+		}
+		raw := strings.TrimSpace(trimmed[colon+1:])
+		if raw == "" {
+			//Kljuc bez vrednosti otvara sekciju, njeni kljucevi su u sledecim, vise uvucenim redovima
+			sections = append(sections, section{indent: indent, prefix: key})
+			opened = true
+			continue
+		}
+		value, err := unquoteYAML(raw)
+		if err != nil {
+			return nil, fmt.Errorf("red %d: %s", number, err)
+		}
+		if _, duplicate := values[key]; duplicate {
+			return nil, fmt.Errorf("red %d: kljuc %s je vec naveden", number, key)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if opened {
+		return nil, fmt.Errorf("sekcija %s je prazna", sections[len(sections)-1].prefix)
+	}
+	return values, nil
+}
+
+//Uklanja komentar - # na pocetku reda ili posle razmaka, van navodnika
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quote != 0:
+			if line[i] == '\\' && quote == '"' {
+				i++
+			} else if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		case line[i] == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteYAML(raw string) (string, error) {
+	switch raw[0] {
+	case '"':
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("neispravan string %s", raw)
+		}
+		return value, nil
+	case '\'':
+		if len(raw) < 2 || raw[len(raw)-1] != '\'' {
+			return "", fmt.Errorf("neispravan string %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case '[', '{', '&', '*', '|', '>':
+		return "", fmt.Errorf("vrednost %s nije podrzana", raw)
+	}
+	return raw, nil
+}
+
+/*JSON konfiguracija - objekti su sekcije, a vrednosti brojevi, stringovi ili bool*/
+func parseJSON(reader io.Reader) (map[string]string, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	return values, flattenJSON(document, "", values)
+}
+
+func flattenJSON(object map[string]interface{}, prefix string, values map[string]string) error {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if err := flattenJSON(value, key, values); err != nil {
+				return err
+			}
+		case json.Number:
+			values[key] = value.String()
+		case string:
+			values[key] = value
+		case bool:
+			values[key] = strconv.FormatBool(value)
+		default:
+			return fmt.Errorf("%s: vrednost mora biti broj, string ili bool", key)
+		}
+	}
+	return nil
+}
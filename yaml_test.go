@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//Ugnjezdene sekcije, komentari, navodnici i povratak na spoljasnju sekciju
+func TestParseYAML(t *testing.T) {
+	document := `# komentar
+---
+wal:
+  batchSize: 1 # komentar posle vrednosti
+  segmentSize: 2
+
+cache:
+    policy: "lru # nije komentar"
+    name: 'it''s'
+    escaped: "a\tb"
+    nested:
+      deep: x#y
+    after: 3
+top: vrednost
+`
+	values, err := parseYAML(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"wal.batchSize":     "1",
+		"wal.segmentSize":   "2",
+		"cache.policy":      "lru # nije komentar",
+		"cache.name":        "it's",
+		"cache.escaped":     "a\tb",
+		"cache.nested.deep": "x#y",
+		"cache.after":       "3",
+		"top":               "vrednost",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("ocekivano %v, dobijeno %v", expected, values)
+	}
+}
+
+//Neispravan ili nepodrzan YAML se odbija sa brojem reda
+func TestParseYAMLErrors(t *testing.T) {
+	documents := map[string]string{
+		"tab":                    "wal:\n\tbatchSize: 1\n",
+		"lista":                  "wal:\n  - 1\n",
+		"uvlacenje":              "wal:\n  batchSize: 1\n   segmentSize: 2\n",
+		"prazna sekcija":         "wal:\ncache:\n  limit: 1\n",
+		"prazna poslednja":       "wal:\n  batchSize: 1\ncache:\n",
+		"dupli kljuc":            "wal:\n  batchSize: 1\n  batchSize: 2\n",
+		"bez razmaka":            "wal:\n  batchSize:1\n",
+		"bez dvotacke":           "wal\n",
+		"lista u redu":           "wal:\n  batchSize: [1, 2]\n",
+		"sidro":                  "wal:\n  batchSize: &a 1\n",
+		"nezatvoren string":      "wal:\n  batchSize: \"1\n",
+		"nezatvoren jednostruki": "wal:\n  batchSize: '1\n",
+	}
+	for name, document := range documents {
+		if values, err := parseYAML(strings.NewReader(document)); err == nil {
+			t.Errorf("%s: ocekivana greska, dobijeno %v", name, values)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	values, err := parseJSON(strings.NewReader(`{"wal": {"batchSize": 1, "segmentSize": 2.5}, "cache": {"policy": "arc", "warmup": true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"wal.batchSize": "1", "wal.segmentSize": "2.5", "cache.policy": "arc", "cache.warmup": "true"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("ocekivano %v, dobijeno %v", expected, values)
+	}
+	if _, err := parseJSON(strings.NewReader(`{"wal": {"batchSize": [1]}}`)); err == nil {
+		t.Fatal("ocekivana greska za listu")
+	}
+}